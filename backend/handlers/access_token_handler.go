@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type AccessTokenHandler struct {
+	Service *services.AccessTokenService
+}
+
+// CreateToken handles POST /api/users/tokens
+func (h *AccessTokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	token, err := h.Service.CreateToken(middleware.GetUserID(r), req)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, token)
+}
+
+// GetTokens handles GET /api/users/tokens
+func (h *AccessTokenHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.Service.GetTokens(middleware.GetUserID(r))
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if tokens == nil {
+		tokens = []models.AccessToken{}
+	}
+	utils.Success(w, tokens)
+}
+
+// RevokeToken handles DELETE /api/users/tokens/{id}
+func (h *AccessTokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	tokenID := mux.Vars(r)["id"]
+	if err := h.Service.RevokeToken(middleware.GetUserID(r), tokenID); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "access token revoked"})
+}
@@ -27,7 +27,9 @@ func (h *BalanceHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
 func (h *BalanceHandler) GetUserBalance(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
-	balances, err := h.Service.GetUserOverallBalance(userID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+	balances, err := h.Service.GetUserOverallBalance(userID, includeArchived)
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
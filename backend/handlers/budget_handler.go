@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type BudgetHandler struct {
+	Service *services.BudgetService
+}
+
+// CreateBudget handles POST /api/groups/{id}/budgets
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	var req models.CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	budget, err := h.Service.CreateBudget(groupID, userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, budget)
+}
+
+// GetBudgets handles GET /api/groups/{id}/budgets
+func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	statuses, err := h.Service.GetBudgets(groupID, userID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if statuses == nil {
+		statuses = []models.BudgetStatus{}
+	}
+
+	utils.Success(w, statuses)
+}
+
+// DeleteBudget handles DELETE /api/groups/{id}/budgets/{budgetId}
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	budgetID := mux.Vars(r)["budgetId"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.DeleteBudget(groupID, budgetID, userID); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "budget deleted"})
+}
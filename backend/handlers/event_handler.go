@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"splitwise/middleware"
+	"splitwise/services"
+	"splitwise/utils"
+)
+
+// streamHeartbeat keeps idle connections from being closed by proxies.
+const streamHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	Events  *services.EventBus
+	Tickets *services.StreamTicketService
+}
+
+// CreateTicket handles POST /api/events/ticket
+func (h *EventHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, err := h.Tickets.Issue(middleware.GetUserID(r), middleware.GetSessionID(r))
+	if err != nil {
+		utils.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.Success(w, ticket)
+}
+
+// Stream handles GET /api/events as a Server-Sent Events stream of activity
+// in the caller's groups.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.Error(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	events, stop := h.Events.Subscribe(userID)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
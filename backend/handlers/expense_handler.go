@@ -3,7 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
+	"splitwise/middleware"
 	"splitwise/models"
 	"splitwise/services"
 	"splitwise/utils"
@@ -15,30 +17,48 @@ type ExpenseHandler struct {
 	Service *services.ExpenseService
 }
 
-func (h *ExpenseHandler) AddExpense(w http.ResponseWriter, r *http.Request) {
-	groupID := mux.Vars(r)["id"]
-
+// decodeExpenseRequest reads and validates the body shared by create and update.
+func decodeExpenseRequest(w http.ResponseWriter, r *http.Request) (models.AddExpenseRequest, bool) {
 	var req models.AddExpenseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.Error(w, http.StatusBadRequest, "invalid request body")
-		return
+		return req, false
 	}
 
 	if req.Amount <= 0 {
 		utils.Error(w, http.StatusBadRequest, "amount must be greater than 0")
-		return
+		return req, false
 	}
 	if req.PaidBy == "" {
 		utils.Error(w, http.StatusBadRequest, "paid_by is required")
-		return
+		return req, false
 	}
-	if req.SplitsType != "equal" && len(req.Splits) == 0 {
+	if req.SplitsType != "equal" && req.SplitsType != "" && len(req.Splits) == 0 {
 		utils.Error(w, http.StatusBadRequest, "splits required for custom split")
+		return req, false
+	}
+	return req, true
+}
+
+func (h *ExpenseHandler) AddExpense(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	req, ok := decodeExpenseRequest(w, r)
+	if !ok {
 		return
 	}
 
-	expense, err := h.Service.AddExpense(groupID, req)
+	expense, err := h.Service.AddExpense(groupID, userID, req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "group is archived" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -46,6 +66,37 @@ func (h *ExpenseHandler) AddExpense(w http.ResponseWriter, r *http.Request) {
 	utils.Success(w, expense)
 }
 
+// UpdateExpense handles PUT /api/expenses/{id}
+func (h *ExpenseHandler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
+	expenseID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	req, ok := decodeExpenseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	expense, err := h.Service.UpdateExpense(expenseID, userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "expense not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err.Error() == "group is archived" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, expense)
+}
+
 func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 	groupID := mux.Vars(r)["id"]
 
@@ -60,8 +111,17 @@ func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 
 func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 	expenseID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
 
-	if err := h.Service.DeleteExpense(expenseID); err != nil {
+	if err := h.Service.DeleteExpense(expenseID, userID); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "group is archived" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"splitwise/middleware"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type ExportHandler struct {
+	Service *services.ExportService
+}
+
+// RequestExport handles POST /api/users/export
+func (h *ExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Service.RequestExport(middleware.GetUserID(r))
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, job)
+}
+
+// GetExport handles GET /api/users/export/{id}
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Service.GetExport(middleware.GetUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		utils.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	utils.Success(w, job)
+}
+
+// Download handles GET /api/exports/download?token=
+func (h *ExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	job, archive, err := h.Service.OpenDownload(r.URL.Query().Get("token"))
+	if err != nil {
+		if err.Error() == "download link has expired" {
+			utils.Error(w, http.StatusGone, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="splitwise-export-`+job.CreatedAt.Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(job.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	io.Copy(w, archive)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"splitwise/middleware"
 	"splitwise/models"
@@ -19,7 +20,9 @@ type GroupHandler struct {
 func (h *GroupHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
-	groups, err := h.Service.GetGroupsByUserID(userID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+	groups, err := h.Service.GetGroupsByUserID(userID, includeArchived)
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
@@ -83,7 +86,7 @@ func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Service.UpdateGroup(groupID, userID, req); err != nil {
-		if err.Error() == "only the group creator can update the group" {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
 			utils.Error(w, http.StatusForbidden, err.Error())
 			return
 		}
@@ -99,7 +102,7 @@ func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 
 	if err := h.Service.DeleteGroup(groupID, userID); err != nil {
-		if err.Error() == "only the group creator can delete the group" {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
 			utils.Error(w, http.StatusForbidden, err.Error())
 			return
 		}
@@ -121,7 +124,8 @@ func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Service.AddMember(groupID, userID, req); err != nil {
-		if err.Error() == "you are not a member of this group" {
+		if err.Error() == "you are not a member of this group" ||
+			strings.HasPrefix(err.Error(), "you do not have permission") {
 			utils.Error(w, http.StatusForbidden, err.Error())
 			return
 		}
@@ -137,15 +141,192 @@ func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	targetUID := mux.Vars(r)["uid"]
 
-	if err := h.Service.RemoveMember(groupID, userID, targetUID); err != nil {
-		if err.Error() == "cannot remove the group creator" ||
-			err.Error() == "only the group creator or the member themselves can remove a member" {
+	force := r.URL.Query().Get("force") == "true"
+
+	if err := h.Service.RemoveMember(groupID, userID, targetUID, force); err != nil {
+		if err.Error() == "cannot remove the group owner" ||
+			strings.HasPrefix(err.Error(), "you do not have permission") {
 			utils.Error(w, http.StatusForbidden, err.Error())
 			return
 		}
+		if strings.HasPrefix(err.Error(), "member has an outstanding balance") {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	utils.Success(w, map[string]string{"message": "member removed"})
 }
+
+// SetMemberRole handles PUT /api/groups/{id}/members/{uid}/role
+func (h *GroupHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+	targetUID := mux.Vars(r)["uid"]
+
+	var req models.UpdateMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.Service.SetMemberRole(groupID, userID, targetUID, req); err != nil {
+		if err.Error() == "the owner's role cannot be changed" ||
+			strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "member role updated"})
+}
+
+// TransferOwnership handles POST /api/groups/{id}/ownership
+func (h *GroupHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	var req models.TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.Service.TransferOwnership(groupID, userID, req); err != nil {
+		if err.Error() == "only the group owner can transfer ownership" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "ownership transfer requested"})
+}
+
+// AcceptOwnership handles PUT /api/groups/{id}/ownership/accept
+func (h *GroupHandler) AcceptOwnership(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.AcceptOwnership(groupID, userID); err != nil {
+		if err.Error() == "no ownership transfer is pending for you" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "ownership transferred"})
+}
+
+// CancelOwnershipTransfer handles DELETE /api/groups/{id}/ownership
+func (h *GroupHandler) CancelOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.CancelOwnershipTransfer(groupID, userID); err != nil {
+		if err.Error() == "only the group owner or the offered member can cancel the transfer" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "ownership transfer cancelled"})
+}
+
+// ReclaimOwnership handles POST /api/groups/{id}/ownership/reclaim
+func (h *GroupHandler) ReclaimOwnership(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.ReclaimOwnership(groupID, userID); err != nil {
+		if err.Error() == "only a group admin can reclaim ownership" ||
+			err.Error() == "the group owner's account is still active" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "ownership reclaimed"})
+}
+
+// ArchiveGroup handles PUT /api/groups/{id}/archive
+func (h *GroupHandler) ArchiveGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	// The zero-balance requirement is optional, so an empty body is fine
+	var req models.ArchiveGroupRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	if err := h.Service.ArchiveGroup(groupID, userID, req); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "group still has outstanding balances" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "group archived"})
+}
+
+// UnarchiveGroup handles PUT /api/groups/{id}/unarchive
+func (h *GroupHandler) UnarchiveGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.UnarchiveGroup(groupID, userID); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "group unarchived"})
+}
+
+// SetDefaultSplit handles PUT /api/groups/{id}/default-split
+func (h *GroupHandler) SetDefaultSplit(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	var req models.UpdateDefaultSplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	config, err := h.Service.SetDefaultSplit(groupID, userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, config)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type InviteHandler struct {
+	Service *services.InviteService
+}
+
+// CreateInvite handles POST /api/groups/{id}/invites
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	// Both settings are optional, so an empty body is fine
+	var req models.CreateInviteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	invite, err := h.Service.CreateInvite(groupID, userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, invite)
+}
+
+// GetInvites handles GET /api/groups/{id}/invites
+func (h *InviteHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	invites, err := h.Service.GetActiveInvites(groupID, userID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if invites == nil {
+		invites = []models.GroupInvite{}
+	}
+
+	utils.Success(w, invites)
+}
+
+// RevokeInvite handles DELETE /api/groups/{id}/invites/{inviteId}
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	inviteID := mux.Vars(r)["inviteId"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.RevokeInvite(groupID, inviteID, userID); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "invite revoked"})
+}
+
+// Join handles POST /api/invites/{token}/join
+func (h *InviteHandler) Join(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	userID := middleware.GetUserID(r)
+
+	group, err := h.Service.JoinByToken(token, userID)
+	if err != nil {
+		if err.Error() == "invite is invalid or has expired" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, group)
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"splitwise/utils"
+)
+
+// JWKS handles GET /.well-known/jwks.json. It uses the standard JWKS shape
+// rather than the API envelope so JWT libraries can consume it directly.
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := utils.PublicJWKs()
+	if err != nil {
+		utils.Error(w, http.StatusInternalServerError, "signing keys unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
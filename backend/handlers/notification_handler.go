@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationHandler struct {
+	Service *services.NotificationService
+}
+
+// GetNotifications handles GET /api/notifications?unread=true
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.Service.GetNotifications(userID, unreadOnly)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	utils.Success(w, notifications)
+}
+
+// UnreadCount handles GET /api/notifications/unread-count
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	count, err := h.Service.UnreadCount(userID)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]int64{"unread": count})
+}
+
+// MarkRead handles PUT /api/notifications/{id}/read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.MarkRead(userID, notificationID); err != nil {
+		if err.Error() == "notification not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "notification marked as read"})
+}
+
+// MarkAllRead handles PUT /api/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.MarkAllRead(userID); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "all notifications marked as read"})
+}
+
+// GetPreferences handles GET /api/notifications/preferences
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	prefs, err := h.Service.GetPreferences(userID)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, prefs)
+}
+
+// UpdatePreferences handles PUT /api/notifications/preferences
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	prefs, err := h.Service.UpdatePreferences(userID, req)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, prefs)
+}
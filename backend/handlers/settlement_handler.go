@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"splitwise/middleware"
 	"splitwise/models"
@@ -25,7 +26,7 @@ func (h *SettlementHandler) Settle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Amount <= 0 {
+	if !req.Full && req.Amount <= 0 {
 		utils.Error(w, http.StatusBadRequest, "amount must be greater than 0")
 		return
 	}
@@ -38,8 +39,25 @@ func (h *SettlementHandler) Settle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	settlement, err := h.Service.Settle(groupID, req)
+	settlement, err := h.Service.Settle(groupID, middleware.GetUserID(r), req)
 	if err != nil {
+		if err.Error() == "group not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "payer and payee must be members of the group" ||
+			err.Error() == "nothing is owed between these users" ||
+			strings.HasPrefix(err.Error(), "method ") ||
+			strings.HasPrefix(err.Error(), "app ") ||
+			strings.HasPrefix(err.Error(), "paid_at ") ||
+			strings.HasPrefix(err.Error(), "settlement exceeds the outstanding amount") {
+			utils.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -47,10 +65,44 @@ func (h *SettlementHandler) Settle(w http.ResponseWriter, r *http.Request) {
 	utils.Success(w, settlement)
 }
 
+// SettleAll handles POST /api/groups/{id}/settle-all
+func (h *SettlementHandler) SettleAll(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	// The ledger version is optional, so an empty body is fine
+	var req models.SettleAllRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	settlements, err := h.Service.SettleAll(groupID, userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "group not found":
+			utils.Error(w, http.StatusNotFound, err.Error())
+		case "you do not have permission to settle all debts":
+			utils.Error(w, http.StatusForbidden, err.Error())
+		case "group balances changed since the plan was read":
+			utils.Error(w, http.StatusConflict, err.Error())
+		case "invalid group id", "confirm or reject pending settlements first", "group is already settled up":
+			utils.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			utils.Error(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.Success(w, settlements)
+}
+
 func (h *SettlementHandler) GetGroupSettlements(w http.ResponseWriter, r *http.Request) {
 	groupID := mux.Vars(r)["id"]
 
-	settlements, err := h.Service.GetGroupSettlements(groupID)
+	settlements, err := h.Service.GetGroupSettlements(groupID, r.URL.Query().Get("method"))
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
@@ -59,10 +111,83 @@ func (h *SettlementHandler) GetGroupSettlements(w http.ResponseWriter, r *http.R
 	utils.Success(w, settlements)
 }
 
+// GetPendingSettlements handles GET /api/groups/{id}/settlements/pending
+func (h *SettlementHandler) GetPendingSettlements(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["id"]
+
+	settlements, err := h.Service.GetPendingSettlements(groupID)
+	if err != nil {
+		utils.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if settlements == nil {
+		settlements = []models.Settlement{}
+	}
+
+	utils.Success(w, settlements)
+}
+
+// ConfirmSettlement handles PUT /api/settlements/{id}/confirm
+func (h *SettlementHandler) ConfirmSettlement(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	settlementID := mux.Vars(r)["id"]
+
+	if err := h.Service.ConfirmSettlement(userID, settlementID); err != nil {
+		if err.Error() == "you can only confirm settlements paid to you" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "settlement is no longer pending" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "settlement confirmed"})
+}
+
+// RejectSettlement handles PUT /api/settlements/{id}/reject
+func (h *SettlementHandler) RejectSettlement(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	settlementID := mux.Vars(r)["id"]
+
+	// The reason is optional, so an empty body is fine
+	var req models.RejectSettlementRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	if err := h.Service.RejectSettlement(userID, settlementID, req); err != nil {
+		if err.Error() == "you can only reject settlements paid to you" {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "settlement is no longer pending" {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "settlement rejected"})
+}
+
 func (h *SettlementHandler) DeleteSettlement(w http.ResponseWriter, r *http.Request) {
 	settlementID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
 
-	if err := h.Service.DeleteSettlement(settlementID); err != nil {
+	if err := h.Service.DeleteSettlement(settlementID, userID); err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
 		utils.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -72,6 +197,7 @@ func (h *SettlementHandler) DeleteSettlement(w http.ResponseWriter, r *http.Requ
 func (h *SettlementHandler) GetUserSettlements(w http.ResponseWriter, r *http.Request) {
 	settlements, err := h.Service.GetUserSettlements(
 		middleware.GetUserID(r),
+		r.URL.Query().Get("method"),
 	)
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/utils"
+)
+
+// LoginTwoFactor handles POST /api/users/login/2fa
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		utils.Error(w, http.StatusBadRequest, "challenge_token and code are required")
+		return
+	}
+	tokens, err := h.Service.LoginTwoFactor(req, clientInfo(r))
+	if err != nil {
+		utils.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	utils.Success(w, tokens)
+}
+
+// SetupTwoFactor handles POST /api/users/2fa/setup
+func (h *UserHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	setup, err := h.Service.SetupTwoFactor(middleware.GetUserID(r))
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, setup)
+}
+
+// ConfirmTwoFactor handles POST /api/users/2fa/confirm
+func (h *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Code == "" {
+		utils.Error(w, http.StatusBadRequest, "code is required")
+		return
+	}
+	codes, err := h.Service.ConfirmTwoFactor(middleware.GetUserID(r), req)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, codes)
+}
+
+// DisableTwoFactor handles POST /api/users/2fa/disable
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Password == "" || req.Code == "" {
+		utils.Error(w, http.StatusBadRequest, "password and code are required")
+		return
+	}
+	if err := h.Service.DisableTwoFactor(middleware.GetUserID(r), req); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /api/users/2fa/recovery-codes
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Code == "" {
+		utils.Error(w, http.StatusBadRequest, "code is required")
+		return
+	}
+	codes, err := h.Service.RegenerateRecoveryCodes(middleware.GetUserID(r), req)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, codes)
+}
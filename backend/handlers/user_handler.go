@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"splitwise/middleware"
 	"splitwise/models"
@@ -11,7 +12,8 @@ import (
 )
 
 type UserHandler struct {
-	Service *services.UserService
+	Service  *services.UserService
+	Sessions *services.SessionService
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -41,12 +43,123 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		utils.Error(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	token, err := h.Service.Login(req)
+	tokens, err := h.Service.Login(req, clientInfo(r))
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "too many"):
+			utils.Error(w, http.StatusTooManyRequests, err.Error())
+		case strings.HasPrefix(err.Error(), "account is temporarily locked"):
+			utils.Error(w, http.StatusLocked, err.Error())
+		default:
+			utils.Error(w, http.StatusUnauthorized, err.Error())
+		}
+		return
+	}
+	utils.Success(w, tokens)
+}
+
+// ChangePassword handles PUT /api/users/password
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		utils.Error(w, http.StatusBadRequest, "current_password and new_password are required")
+		return
+	}
+	if err := h.Service.ChangePassword(middleware.GetUserID(r), middleware.GetSessionID(r), req); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "password changed"})
+}
+
+// ChangeEmail handles PUT /api/users/email
+func (h *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.NewEmail == "" || req.CurrentPassword == "" {
+		utils.Error(w, http.StatusBadRequest, "new_email and current_password are required")
+		return
+	}
+	if err := h.Service.ChangeEmail(middleware.GetUserID(r), middleware.GetSessionID(r), req); err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "please wait"), strings.HasPrefix(err.Error(), "too many"):
+			utils.Error(w, http.StatusTooManyRequests, err.Error())
+		default:
+			utils.Error(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	utils.Success(w, map[string]string{"message": "check your new email address to confirm the change"})
+}
+
+// DeleteAccount handles DELETE /api/users/account
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Password == "" {
+		utils.Error(w, http.StatusBadRequest, "password is required")
+		return
+	}
+	if err := h.Service.DeleteAccount(middleware.GetUserID(r), req); err != nil {
+		if strings.HasPrefix(err.Error(), "you have outstanding balances") {
+			utils.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "account deleted"})
+}
+
+// Refresh handles POST /api/users/refresh
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.Error(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+	tokens, err := h.Sessions.Refresh(req)
 	if err != nil {
 		utils.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
-	utils.Success(w, map[string]string{"token": token})
+	utils.Success(w, tokens)
+}
+
+// Logout handles POST /api/users/logout
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.Sessions.Logout(middleware.GetSessionID(r)); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "logged out"})
+}
+
+// LogoutAll handles POST /api/users/logout-all
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if err := h.Sessions.LogoutAll(middleware.GetUserID(r)); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "logged out of all sessions"})
+}
+
+func clientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{UserAgent: r.UserAgent(), IP: utils.ClientIP(r)}
 }
 func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	users, err := h.Service.GetAll()
@@ -90,17 +203,49 @@ func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 		utils.Error(w, http.StatusBadRequest, "email is required")
 		return
 	}
-	token, err := h.Service.ForgotPassword(req)
-	if err != nil {
-		utils.Error(w, http.StatusBadRequest, err.Error())
+	// Same answer either way so the endpoint can't be used to probe for accounts
+	if err := h.Service.ForgotPassword(req, utils.ClientIP(r)); err != nil {
+		utils.Error(w, http.StatusTooManyRequests, err.Error())
 		return
 	}
 	utils.Success(w, map[string]string{
-		"message": "password reset token generated",
-		"token":   token,
+		"message": "if an account exists for that email, a password reset link has been sent",
 	})
 }
 
+// VerifyEmail handles POST /api/users/verify-email
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Token == "" {
+		utils.Error(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := h.Service.VerifyEmail(req); err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.Success(w, map[string]string{"message": "email verified"})
+}
+
+// ResendVerification handles POST /api/users/verify-email/resend
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if err := h.Service.ResendVerification(userID); err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "please wait"), strings.HasPrefix(err.Error(), "too many"):
+			utils.Error(w, http.StatusTooManyRequests, err.Error())
+		default:
+			utils.Error(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	utils.Success(w, map[string]string{"message": "verification email sent"})
+}
+
 func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	var req models.ResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"splitwise/middleware"
+	"splitwise/models"
+	"splitwise/services"
+	"splitwise/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	Service *services.WebhookService
+}
+
+// CreateWebhook handles POST /api/webhooks
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	hook, err := h.Service.CreateWebhook(userID, req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "you do not have permission") {
+			utils.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, hook)
+}
+
+// GetWebhooks handles GET /api/webhooks
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	hooks, err := h.Service.GetWebhooks(userID)
+	if err != nil {
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+
+	utils.Success(w, hooks)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	if err := h.Service.DeleteWebhook(userID, webhookID); err != nil {
+		if err.Error() == "webhook not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, map[string]string{"message": "webhook deleted"})
+}
+
+// GetDeliveries handles GET /api/webhooks/{id}/deliveries
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	deliveries, err := h.Service.GetDeliveries(userID, webhookID)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	utils.Success(w, deliveries)
+}
+
+// Ping handles POST /api/webhooks/{id}/ping
+func (h *WebhookHandler) Ping(w http.ResponseWriter, r *http.Request) {
+	webhookID := mux.Vars(r)["id"]
+	userID := middleware.GetUserID(r)
+
+	delivery, err := h.Service.Ping(userID, webhookID)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			utils.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.Success(w, delivery)
+}
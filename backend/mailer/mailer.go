@@ -0,0 +1,53 @@
+// Package mailer sends transactional email. The SMTP mailer delivers real
+// mail; the outbox mailer writes messages to disk (or the log) so
+// development and tests never send anything.
+package mailer
+
+import (
+	"os"
+	"strconv"
+)
+
+// Message is a multipart email with plain-text and HTML bodies.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv builds the mailer named by MAIL_DRIVER: "smtp" for SMTP,
+// anything else for the outbox.
+func NewFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Splitwise <no-reply@localhost>"
+	}
+
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+	return &OutboxMailer{Dir: os.Getenv("MAIL_OUTBOX_DIR"), From: from}
+}
+
+// BaseURL is the frontend address used to build links in emails.
+func BaseURL() string {
+	if url := os.Getenv("APP_BASE_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:5173"
+}
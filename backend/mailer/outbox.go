@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outboxKeep is how many recent messages an OutboxMailer keeps in memory.
+const outboxKeep = 100
+
+// OutboxMailer keeps sent messages instead of delivering them. Each one is
+// written to Dir as an .eml file, or logged when Dir is empty, and the most
+// recent ones are also kept in memory for tests to inspect.
+type OutboxMailer struct {
+	Dir  string
+	From string
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *OutboxMailer) Send(msg Message) error {
+	m.mu.Lock()
+	m.sent = append(m.sent, msg)
+	if len(m.sent) > outboxKeep {
+		m.sent = append([]Message(nil), m.sent[len(m.sent)-outboxKeep:]...)
+	}
+	m.mu.Unlock()
+
+	if m.Dir == "" {
+		log.Printf("Outbox mail to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+		return nil
+	}
+
+	data, err := build(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o644)
+}
+
+// Sent returns the last outboxKeep messages sent, oldest first.
+func (m *OutboxMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// sanitize makes an address safe to use in a file name.
+func sanitize(address string) string {
+	out := []rune(address)
+	for i, r := range out {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '@') {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer sends mail through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+
+	data, err := build(m.From, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{msg.To}, data)
+}
@@ -6,11 +6,18 @@ import (
 	"os"
 
 	"splitwise/config"
+	"splitwise/repository"
 	"splitwise/router"
+	"splitwise/utils"
 )
 
 func main() {
 	config.Connect()
+	if err := utils.LoadKeys(); err != nil {
+		log.Fatal("Invalid JWT key configuration: ", err)
+	}
+	repository.RunMigrations()
+	repository.EnsureIndexes()
 	r := router.SetupRouter()
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -4,10 +4,21 @@ import (
 	"net/http"
 	"strings"
 
+	"splitwise/models"
 	"splitwise/utils"
 )
 type contextKey string
 const UserIDKey contextKey = "userID"
+const SessionIDKey contextKey = "sessionID"
+
+// ValidateSession reports whether the session behind an access token is
+// still live. It is set by the router; when nil only the signature and
+// expiry of the token are checked.
+var ValidateSession func(userID, sessionID string) error
+
+// AuthenticateAccessToken lets personal access tokens stand in for a JWT.
+// It returns the token's owner and scopes.
+var AuthenticateAccessToken func(token, ip string) (string, []string, error)
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -24,16 +35,43 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			utils.Error(w, http.StatusUnauthorized, "Token missing")
 			return
 		}
-		userID, err := utils.ParseJWT(token)
+		if AuthenticateAccessToken != nil && strings.HasPrefix(token, models.AccessTokenPrefix) {
+			userID, scopes, err := AuthenticateAccessToken(token, utils.ClientIP(r))
+			if err != nil {
+				utils.Error(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			if !scopesAllow(scopes, r) {
+				utils.Error(w, http.StatusForbidden, "access token does not have the scope for this request")
+				return
+			}
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		userID, sessionID, err := utils.ParseJWT(token)
 		if err != nil {
 			utils.Error(w, http.StatusUnauthorized, "invalid token")
 			return
 		}
+		if ValidateSession != nil {
+			if err := ValidateSession(userID, sessionID); err != nil {
+				utils.Error(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 func GetUserID(r *http.Request) (string){
 	userID,_:= r.Context().Value(UserIDKey).(string)
 	return userID
-}
\ No newline at end of file
+}
+
+// GetSessionID returns the session the request's access token belongs to.
+func GetSessionID(r *http.Request) string {
+	sessionID, _ := r.Context().Value(SessionIDKey).(string)
+	return sessionID
+}
@@ -2,12 +2,35 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 )
+
+// secretParams are query parameters that carry credentials and must not
+// reach the access log.
+var secretParams = []string{"access_token", "ticket", "token"}
+
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("[%s] %s %s-%v", r.Method, r.RequestURI,r.RemoteAddr, time.Since(start),)
+		log.Printf("[%s] %s %s-%v", r.Method, loggedURI(r),r.RemoteAddr, time.Since(start),)
 	})
-}
\ No newline at end of file
+}
+
+// loggedURI is the request URI with secret query parameters redacted.
+func loggedURI(r *http.Request) string {
+	query := r.URL.Query()
+	redacted := false
+	for _, name := range secretParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return r.RequestURI
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.RequestURI()
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"splitwise/models"
+
+	"github.com/gorilla/mux"
+)
+
+// scopeRoutes lists the routes, as "METHOD path-template", that each scope
+// opens to access tokens besides reads. Anything not listed needs a session.
+var scopeRoutes = map[string][]string{
+	models.ScopeRead: {
+		"POST /api/events/ticket",
+	},
+	models.ScopeWriteExpenses: {
+		"POST /api/groups/{id}/expenses",
+		"PUT /api/expenses/{id}",
+		"DELETE /api/expenses/{id}",
+	},
+}
+
+// scopesAllow reports whether an access token with scopes may make request r.
+func scopesAllow(scopes []string, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return hasScope(scopes, models.ScopeRead)
+	}
+
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	key := r.Method + " " + template
+	for _, scope := range scopes {
+		for _, allowed := range scopeRoutes[scope] {
+			if allowed == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
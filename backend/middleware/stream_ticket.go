@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"splitwise/utils"
+)
+
+// RedeemStreamTicket uses up a stream ticket and returns the user and
+// session it was issued to. It is set by the router.
+var RedeemStreamTicket func(ticket string) (string, string, error)
+
+// StreamTicketMiddleware authenticates clients that can't set headers, such
+// as the browser's EventSource, by a single-use ?ticket=. Requests without
+// one go through AuthMiddleware as usual.
+func StreamTicketMiddleware(next http.Handler) http.Handler {
+	authenticated := AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" || RedeemStreamTicket == nil {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+		userID, sessionID, err := RedeemStreamTicket(ticket)
+		if err != nil {
+			utils.Error(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if sessionID != "" && ValidateSession != nil {
+			if err := ValidateSession(userID, sessionID); err != nil {
+				utils.Error(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Access token scopes. A token only gets what its scopes grant; read covers
+// every GET, and write scopes open specific routes.
+const (
+	ScopeRead          = "read"
+	ScopeWriteExpenses = "write:expenses"
+)
+
+// AccessTokenPrefix marks personal access tokens so AuthMiddleware can tell
+// them from JWTs, and makes leaked tokens easy to scan for.
+const AccessTokenPrefix = "swp_"
+
+var AccessTokenScopes = []string{ScopeRead, ScopeWriteExpenses}
+
+// AccessToken is a personal access token for scripts. Only the SHA-256 of
+// the token is stored; Prefix is kept so users can tell tokens apart.
+type AccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"          json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id"                json:"user_id"`
+	Name       string             `bson:"name"                   json:"name"`
+	Prefix     string             `bson:"prefix"                 json:"prefix"`
+	TokenHash  string             `bson:"token_hash"             json:"-"`
+	Scopes     []string           `bson:"scopes"                 json:"scopes"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty"   json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at"             json:"created_at"`
+}
+
+// CreateAccessTokenRequest.ExpiresInDays of 0 means the token never expires.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreatedAccessToken is returned once, on creation; Token can't be
+// retrieved again.
+type CreatedAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
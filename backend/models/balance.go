@@ -1,7 +1,11 @@
 package models
 
+// BalanceDetail is one transfer in a simplified plan. The former member flags
+// mark users who were removed from the group with a balance outstanding.
 type BalanceDetail struct {
-	FromUserID string  `json:"from_user"`
-	ToUser     string  `json:"to_user"`
-	Amount     float64 `json:"amount"`
+	FromUserID       string  `json:"from_user"`
+	ToUser           string  `json:"to_user"`
+	Amount           float64 `json:"amount"`
+	FromFormerMember bool    `json:"from_former_member,omitempty"`
+	ToFormerMember   bool    `json:"to_former_member,omitempty"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Budget caps a group's spending over a date range. An empty Category
+// budgets every expense in the group.
+type Budget struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"      json:"id"`
+	GroupID    primitive.ObjectID `bson:"group_id"           json:"group_id"`
+	Category   string             `bson:"category,omitempty" json:"category,omitempty"`
+	Amount     float64            `bson:"amount"             json:"amount"`
+	StartDate  time.Time          `bson:"start_date"         json:"start_date"`
+	EndDate    time.Time          `bson:"end_date"           json:"end_date"`    // inclusive
+	AlertsSent []int              `bson:"alerts_sent"        json:"alerts_sent"` // thresholds (percent) already announced
+	CreatedBy  primitive.ObjectID `bson:"created_by"         json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at"         json:"created_at"`
+}
+
+// CreateBudgetRequest takes dates as YYYY-MM-DD.
+type CreateBudgetRequest struct {
+	Category  string  `json:"category"`
+	Amount    float64 `json:"amount"`
+	StartDate string  `json:"start_date"`
+	EndDate   string  `json:"end_date"`
+}
+
+// BudgetStatus reports how much of a budget has been spent.
+type BudgetStatus struct {
+	Budget      Budget  `json:"budget"`
+	Spent       float64 `json:"spent"`
+	Remaining   float64 `json:"remaining"`
+	PercentUsed float64 `json:"percent_used"`
+}
@@ -1,29 +1,38 @@
 package models
+
 import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
+
 type ExpenseSplit struct {
 	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
 	Amount float64            `bson:"amount"  json:"amount"`
-}	
+}
 type Expense struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	GroupID     primitive.ObjectID `bson:"group_id"      json:"group_id"`
-	PaidBy      primitive.ObjectID `bson:"paid_by"       json:"paid_by"`
-	Amount      float64            `bson:"amount"        json:"amount"`
-    Description string             `bson:"description"   json:"description"`	
-	Splits      []ExpenseSplit      `bson:"splits"        json:"splits"`
-	CreatedAt   time.Time          `bson:"created_at"    json:"created_at"`
+	ID          primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	GroupID     primitive.ObjectID `bson:"group_id"             json:"group_id"`
+	PaidBy      primitive.ObjectID `bson:"paid_by"              json:"paid_by"`
+	Amount      float64            `bson:"amount"               json:"amount"`
+	Description string             `bson:"description"          json:"description"`
+	Category    string             `bson:"category,omitempty"   json:"category,omitempty"`
+	Splits      []ExpenseSplit     `bson:"splits"               json:"splits"`
+	CreatedBy   primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"           json:"created_at"`
+	UpdatedAt   *time.Time         `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
 }
+
+// AddExpenseRequest leaves SplitsType empty and omits splits to use the
+// group's default split.
 type AddExpenseRequest struct {
-	PaidBy	  string  `json:"paid_by"`
-	Amount    float64 `json:"amount"`
+	PaidBy      string  `json:"paid_by"`
+	Amount      float64 `json:"amount"`
 	Description string  `json:"description"`
-	SplitsType string `json:"splits_type"`
-	Splits     []struct{
-	   UserID string  `json:"user_id"`
-	   Amount float64 `json:"amount"`
+	Category    string  `json:"category"`
+	SplitsType  string  `json:"splits_type"`
+	Splits      []struct {
+		UserID string  `json:"user_id"`
+		Amount float64 `json:"amount"`
 	} `json:"splits"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Export job statuses.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// ExportJob builds a ZIP of a user's data in the background. Once ready,
+// the archive can be downloaded until ExpiresAt, after which it is deleted.
+// Each time the owner looks at the job a new download link is issued; only
+// the hash of its token is stored, and it replaces the previous one.
+type ExportJob struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty"          json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id"                json:"user_id"`
+	Status      string              `bson:"status"                 json:"status"`
+	Error       string              `bson:"error,omitempty"        json:"error,omitempty"`
+	FileID      *primitive.ObjectID `bson:"file_id,omitempty"      json:"-"`
+	Size        int64               `bson:"size,omitempty"         json:"size,omitempty"`
+	TokenHash   string              `bson:"token_hash,omitempty"   json:"-"`
+	DownloadURL string              `bson:"-"                      json:"download_url,omitempty"`
+	ExpiresAt   *time.Time          `bson:"expires_at,omitempty"   json:"expires_at,omitempty"`
+	CompletedAt *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at"             json:"created_at"`
+}
@@ -6,19 +6,106 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Member roles, from most to least privileged. A viewer can read the group
+// but never appears in splits or settlements.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// GroupMember is a user's membership in a group.
+type GroupMember struct {
+	UserID   primitive.ObjectID `bson:"user_id"   json:"user_id"`
+	Role     string             `bson:"role"      json:"role"`
+	JoinedAt time.Time          `bson:"joined_at" json:"joined_at"`
+}
+
+// FormerMember records someone who was removed from a group while they still
+// owed or were owed money, so they stay visible in its balances.
+type FormerMember struct {
+	UserID    primitive.ObjectID `bson:"user_id"    json:"user_id"`
+	Role      string             `bson:"role"       json:"role"`
+	Balance   float64            `bson:"balance"    json:"balance"`
+	RemovedBy primitive.ObjectID `bson:"removed_by" json:"removed_by"`
+	RemovedAt time.Time          `bson:"removed_at" json:"removed_at"`
+}
+
+// SplitConfig is a group's default way of dividing an expense.
+// Type: "equal", "shares" or "percentage". For shares each value is a
+// weight (e.g. room size); for percentage the values must add up to 100.
+// NeedsUpdate is set when a membership change leaves the shares no longer
+// covering every participant; the config is kept so owners can fix it, but
+// it isn't applied until they do.
+type SplitConfig struct {
+	Type        string        `bson:"type"             json:"type"`
+	Shares      []MemberShare `bson:"shares,omitempty" json:"shares,omitempty"`
+	NeedsUpdate bool          `bson:"needs_update,omitempty" json:"needs_update,omitempty"`
+}
+
+type MemberShare struct {
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Value  float64            `bson:"value"   json:"value"`
+}
+
+// Group.SettlementPolicy controls what happens when a settlement exceeds
+// what the payer owes: "warn" (default) records it with a warning, "reject"
+// refuses it.
+// Group.PendingOwner is the member who has been offered ownership but hasn't
+// accepted yet.
+// Group.LedgerVersion increases whenever an expense or settlement changes the
+// group's balances, so clients can detect that a plan they read is stale.
+// Group.DefaultSplit is applied to expenses added without explicit splits;
+// nil means split equally.
+// Group.Archived hides a finished group from default listings and overall
+// balances and stops its expenses from changing.
 type Group struct {
-	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Name      string               `bson:"name"          json:"name"`
-	CreatedBy primitive.ObjectID   `bson:"created_by"   json:"created_by"`
-	Members   []primitive.ObjectID `bson:"members"      json:"members"`
-	CreatedAt time.Time            `bson:"created_at"    json:"created_at"`
+	ID               primitive.ObjectID   `bson:"_id,omitempty"     json:"id"`
+	Name             string               `bson:"name"              json:"name"`
+	CreatedBy        primitive.ObjectID   `bson:"created_by"        json:"created_by"`
+	Members          []GroupMember        `bson:"members"           json:"members"`
+	SettlementPolicy string               `bson:"settlement_policy" json:"settlement_policy"`
+	LedgerVersion    int64                `bson:"ledger_version"    json:"ledger_version"`
+	PendingOwner     *primitive.ObjectID  `bson:"pending_owner,omitempty" json:"pending_owner,omitempty"`
+	FormerMembers    []FormerMember       `bson:"former_members,omitempty" json:"former_members,omitempty"`
+	DefaultSplit     *SplitConfig         `bson:"default_split,omitempty" json:"default_split,omitempty"`
+	Archived         bool                 `bson:"archived"          json:"archived"`
+	ArchivedAt       *time.Time           `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	CreatedAt        time.Time            `bson:"created_at"        json:"created_at"`
 }
 type CreateGroupRequest struct {
 	Name string `json:"name"`
 }
+// AddMemberRequest adds an existing user by UserID, or anyone by Name and
+// Email; people without an account are added as placeholder users.
+// Role defaults to "member".
 type AddMemberRequest struct {
 	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role"`
 }
 type UpdateGroupRequest struct {
-	Name string `json:"name"`
+	Name             string `json:"name"`
+	SettlementPolicy string `json:"settlement_policy"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type ArchiveGroupRequest struct {
+	RequireZeroBalance bool `json:"require_zero_balance"`
+}
+
+type UpdateDefaultSplitRequest struct {
+	Type   string `json:"type"`
+	Shares []struct {
+		UserID string  `json:"user_id"`
+		Value  float64 `json:"value"`
+	} `json:"shares"`
 }
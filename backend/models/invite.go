@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupInvite is a shareable token that adds whoever redeems it to a group.
+// MaxUses of 0 means the invite can be used any number of times.
+type GroupInvite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID   primitive.ObjectID `bson:"group_id"      json:"group_id"`
+	Token     string             `bson:"token"         json:"token"`
+	CreatedBy primitive.ObjectID `bson:"created_by"    json:"created_by"`
+	MaxUses   int                `bson:"max_uses"      json:"max_uses"`
+	Uses      int                `bson:"uses"          json:"uses"`
+	ExpiresAt time.Time          `bson:"expires_at"    json:"expires_at"`
+	Revoked   bool               `bson:"revoked"       json:"revoked"`
+	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+}
+
+// CreateInviteRequest.ExpiresInHours defaults to 7 days.
+type CreateInviteRequest struct {
+	ExpiresInHours int `json:"expires_in_hours"`
+	MaxUses        int `json:"max_uses"`
+}
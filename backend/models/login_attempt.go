@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of throttled attempts.
+const (
+	AttemptLogin          = "login"
+	AttemptForgotPassword = "forgot_password"
+)
+
+// LoginAttempt is a failed login or a password reset request, kept for a
+// day to throttle by account (Email) and by IP.
+type LoginAttempt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind      string             `bson:"kind"          json:"kind"`
+	Email     string             `bson:"email"         json:"email"`
+	IP        string             `bson:"ip"            json:"ip"`
+	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+}
+
+// LockoutEvent records an account being locked after repeated failures.
+type LockoutEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id"       json:"user_id"`
+	Email       string             `bson:"email"         json:"email"`
+	IP          string             `bson:"ip"            json:"ip"`
+	Failures    int64              `bson:"failures"      json:"failures"`
+	LockedUntil time.Time          `bson:"locked_until"  json:"locked_until"`
+	CreatedAt   time.Time          `bson:"created_at"    json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification types. Users can mute any of them in their preferences.
+const (
+	NotifyExpenseAdded         = "expense.added"
+	NotifyExpenseUpdated       = "expense.updated"
+	NotifySettlementCreated    = "settlement.created"
+	NotifySettlementConfirmed  = "settlement.confirmed"
+	NotifySettlementRejected   = "settlement.rejected"
+	NotifyAddedToGroup         = "group.member_added"
+	NotifyBudgetAlert          = "group.budget_alert"
+	NotifyDefaultSplitOutdated = "group.default_split_outdated"
+	NotifyFriendRequest        = "friend.request"
+	NotifyFriendRequestAccepts = "friend.accepted"
+)
+
+// NotificationTypes lists every notification type.
+var NotificationTypes = []string{
+	NotifyExpenseAdded,
+	NotifyExpenseUpdated,
+	NotifySettlementCreated,
+	NotifySettlementConfirmed,
+	NotifySettlementRejected,
+	NotifyAddedToGroup,
+	NotifyBudgetAlert,
+	NotifyDefaultSplitOutdated,
+	NotifyFriendRequest,
+	NotifyFriendRequestAccepts,
+}
+
+// Notification is an in-app message for one user. RefID points at the
+// expense, settlement, budget or friend request it is about.
+type Notification struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"      json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id"            json:"user_id"`
+	Type      string              `bson:"type"               json:"type"`
+	Message   string              `bson:"message"            json:"message"`
+	ActorID   *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	GroupID   *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	RefID     *primitive.ObjectID `bson:"ref_id,omitempty"   json:"ref_id,omitempty"`
+	Read      bool                `bson:"read"               json:"read"`
+	CreatedAt time.Time           `bson:"created_at"         json:"created_at"`
+}
+
+// NotificationPreferences records the notification types a user has muted
+// and the ones they also want by email.
+type NotificationPreferences struct {
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Muted  []string           `bson:"muted"   json:"muted"`
+	Email  []string           `bson:"email"   json:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Muted []string `json:"muted"`
+	Email []string `json:"email"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a signed-in device. Access tokens carry the session ID and stop
+// working as soon as the session is revoked. Only hashes of refresh tokens
+// are stored; PreviousHash catches a rotated-out token being replayed.
+type Session struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id"               json:"user_id"`
+	RefreshHash  string             `bson:"refresh_hash"          json:"-"`
+	PreviousHash string             `bson:"previous_hash"         json:"-"`
+	UserAgent    string             `bson:"user_agent"            json:"user_agent"`
+	IP           string             `bson:"ip"                    json:"ip"`
+	ExpiresAt    time.Time          `bson:"expires_at"            json:"expires_at"`
+	LastUsedAt   time.Time          `bson:"last_used_at"          json:"last_used_at"`
+	RevokedAt    *time.Time         `bson:"revoked_at,omitempty"  json:"revoked_at,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at"            json:"created_at"`
+}
+
+// ClientInfo describes where a request came from.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair is returned by login and refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
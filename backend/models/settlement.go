@@ -6,17 +6,53 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Settlement represents a payment from PaidBy to PaidTo.
+// Method: "cash", "bank_transfer", "app" (App names which one). PaidAt is
+// when the money actually changed hands, which may predate CreatedAt.
+// Status: "pending", "confirmed", "rejected". Only confirmed settlements
+// count towards balances; records without a status predate confirmation
+// and are treated as confirmed.
 type Settlement struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	GroupID   primitive.ObjectID `bson:"group_id"      json:"group_id"`
-	PaidBy    primitive.ObjectID `bson:"paid_by"       json:"paid_by"`
-	PaidTo    primitive.ObjectID `bson:"paid_to"       json:"paid_to"`
-	Amount    float64            `bson:"amount"        json:"amount"`
-	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty"           json:"id"`
+	GroupID      primitive.ObjectID `bson:"group_id"                json:"group_id"`
+	PaidBy       primitive.ObjectID `bson:"paid_by"                 json:"paid_by"`
+	PaidTo       primitive.ObjectID `bson:"paid_to"                 json:"paid_to"`
+	Amount       float64            `bson:"amount"                  json:"amount"`
+	Method       string             `bson:"method,omitempty"        json:"method,omitempty"`
+	App          string             `bson:"app,omitempty"           json:"app,omitempty"`
+	Reference    string             `bson:"reference,omitempty"     json:"reference,omitempty"`
+	Note         string             `bson:"note,omitempty"          json:"note,omitempty"`
+	PaidAt       time.Time          `bson:"paid_at"                 json:"paid_at"`
+	Status       string             `bson:"status"                  json:"status"`
+	RejectReason string             `bson:"reject_reason,omitempty" json:"reject_reason,omitempty"`
+	RespondedAt  *time.Time         `bson:"responded_at,omitempty"  json:"responded_at,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at"              json:"created_at"`
+	Warning      string             `bson:"-"                       json:"warning,omitempty"`
 }
 
+// SettleRequest.Full settles the exact outstanding amount between the two
+// users; Amount is ignored when it is set. PaidAt accepts RFC 3339 or
+// YYYY-MM-DD and defaults to now.
 type SettleRequest struct {
-	PaidBy string  `json:"paid_by"`
-	PaidTo string  `json:"paid_to"`
-	Amount float64 `json:"amount"`
+	PaidBy    string  `json:"paid_by"`
+	PaidTo    string  `json:"paid_to"`
+	Amount    float64 `json:"amount"`
+	Full      bool    `json:"full"`
+	Method    string  `json:"method"`
+	App       string  `json:"app"`
+	Reference string  `json:"reference"`
+	Note      string  `json:"note"`
+	PaidAt    string  `json:"paid_at"`
+}
+
+type RejectSettlementRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SettleAllRequest.LedgerVersion is the group's ledger_version the caller saw
+// when reviewing the plan; the call fails if balances changed since then.
+// Archive archives the group once everything is settled.
+type SettleAllRequest struct {
+	LedgerVersion *int64 `json:"ledger_version"`
+	Archive       bool   `json:"archive"`
 }
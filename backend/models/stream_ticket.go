@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StreamTicket lets a client that can't send headers, such as the browser's
+// EventSource, open the event stream without putting a bearer token in the
+// URL. It is valid once, for a few seconds; only its hash is stored.
+type StreamTicket struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty"        json:"id"`
+	UserID     primitive.ObjectID  `bson:"user_id"              json:"user_id"`
+	SessionID  *primitive.ObjectID `bson:"session_id,omitempty" json:"-"`
+	TicketHash string              `bson:"ticket_hash"          json:"-"`
+	ExpiresAt  time.Time           `bson:"expires_at"           json:"expires_at"`
+	CreatedAt  time.Time           `bson:"created_at"           json:"created_at"`
+}
+
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"` // seconds until the ticket expires
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginResult is what login returns: a token pair, or, for accounts with
+// 2FA, a challenge token to send back with a code to /login/2fa.
+type LoginResult struct {
+	*TokenPair
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// LoginChallenge is a password check that still needs a second factor.
+// Only the hash of the challenge token is stored.
+type LoginChallenge struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id"       json:"user_id"`
+	TokenHash string             `bson:"token_hash"    json:"-"`
+	Attempts  int                `bson:"attempts"      json:"attempts"`
+	ExpiresAt time.Time          `bson:"expires_at"    json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // TOTP code or a recovery code
+}
+
+// TwoFactorSetup is shown once when enrollment starts.
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse lists new recovery codes. They are not stored in
+// plain text and can't be shown again.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -6,12 +6,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User.Placeholder marks someone added to a group by name and email who
+// hasn't signed up; they can't log in and are merged into the real account
+// once it verifies that email. Unverified accounts can log in but can't be
+// found, added to groups or sent friend requests.
+//
+// TOTPPendingSecret holds a secret during 2FA enrollment until the user
+// proves their app works; RecoveryCodes are SHA-256 hashes. LockedUntil is
+// set after repeated failed logins and cleared by a password reset.
+//
+// A deleted account keeps its record, anonymized, so expenses and
+// settlements that reference it still add up. DeletionStartedAt is set when
+// deletion begins; from then on the account can't sign in, and a deletion
+// that was interrupted is finished in the background.
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name"          json:"name"`
-	Email     string             `bson:"email"         json:"email"`
-	Password  string             `bson:"password"      json:"-"`
-	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+	ID                primitive.ObjectID `bson:"_id,omitempty"                 json:"id"`
+	Name              string             `bson:"name"                          json:"name"`
+	Email             string             `bson:"email"                         json:"email"`
+	Password          string             `bson:"password"                      json:"-"`
+	Placeholder       bool               `bson:"placeholder,omitempty"         json:"placeholder,omitempty"`
+	EmailVerified     bool               `bson:"email_verified"                json:"email_verified"`
+	TwoFactorEnabled  bool               `bson:"two_factor_enabled"            json:"two_factor_enabled"`
+	TOTPSecret        string             `bson:"totp_secret,omitempty"         json:"-"`
+	TOTPPendingSecret string             `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64              `bson:"totp_last_step,omitempty"      json:"-"`
+	RecoveryCodes     []string           `bson:"recovery_codes,omitempty"      json:"-"`
+	LockedUntil       *time.Time         `bson:"locked_until,omitempty"        json:"-"`
+	Deleted           bool               `bson:"deleted,omitempty"             json:"deleted,omitempty"`
+	DeletedAt         *time.Time         `bson:"deleted_at,omitempty"          json:"deleted_at,omitempty"`
+	DeletionStartedAt *time.Time         `bson:"deletion_started_at,omitempty" json:"-"`
+	CreatedAt         time.Time          `bson:"created_at"                    json:"created_at"`
 }
 
 type RegisterRequest struct {
@@ -27,6 +51,24 @@ type UpdateProfileRequest struct {
 	Name string `json:"name"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// DeleteAccountRequest.AcknowledgeBalances allows deletion while the user
+// still owes or is owed money; those balances stay in their groups.
+type DeleteAccountRequest struct {
+	Password            string `json:"password"`
+	Code                string `json:"code"` // required when 2FA is enabled
+	AcknowledgeBalances bool   `json:"acknowledge_balances"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email"`
+	CurrentPassword string `json:"current_password"`
+}
+
 type ForgotPasswordRequest struct {
 	Email string `json:"email"`
 }
@@ -36,6 +78,25 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// EmailVerification proves the user controls Email. It is tied to the
+// address so a token can't verify a different email after a change.
+// EmailChange marks a request to move the account to Email; SessionID is
+// the session that asked, which stays signed in when the change completes.
+type EmailVerification struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty"          json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id"                json:"user_id"`
+	Email       string              `bson:"email"                  json:"email"`
+	Token       string              `bson:"token"                  json:"token"`
+	EmailChange bool                `bson:"email_change,omitempty" json:"email_change,omitempty"`
+	SessionID   *primitive.ObjectID `bson:"session_id,omitempty"   json:"-"`
+	ExpiresAt   time.Time           `bson:"expires_at"             json:"expires_at"`
+	CreatedAt   time.Time           `bson:"created_at"             json:"created_at"`
+}
+
 type PasswordReset struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id"       json:"user_id"`
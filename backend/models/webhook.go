@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook posts events to an external URL. Group webhooks receive every
+// event in the group; personal ones (no GroupID) receive every event the
+// owner can see. An empty Events list subscribes to all event types.
+type Webhook struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"      json:"id"`
+	OwnerID   primitive.ObjectID  `bson:"owner_id"           json:"owner_id"`
+	GroupID   *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	URL       string              `bson:"url"                json:"url"`
+	Secret    string              `bson:"secret"             json:"secret,omitempty"` // only returned on creation
+	Events    []string            `bson:"events"             json:"events"`
+	CreatedAt time.Time           `bson:"created_at"         json:"created_at"`
+}
+
+// WebhookDelivery is one event sent, or waiting to be sent, to a webhook.
+// Status: "pending", "delivered", "failed"
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	WebhookID     primitive.ObjectID `bson:"webhook_id"            json:"webhook_id"`
+	EventType     string             `bson:"event_type"            json:"event_type"`
+	Payload       string             `bson:"payload"               json:"payload"`
+	Status        string             `bson:"status"                json:"status"`
+	Attempts      int                `bson:"attempts"              json:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"       json:"next_attempt_at"`
+	StatusCode    int                `bson:"status_code,omitempty" json:"status_code,omitempty"` // of the last attempt
+	LastError     string             `bson:"last_error,omitempty"  json:"last_error,omitempty"`
+	DeliveredAt   *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at"            json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL     string   `json:"url"`
+	GroupID string   `json:"group_id"`
+	Events  []string `json:"events"`
+}
@@ -0,0 +1,37 @@
+// Package pubsub fans events out to subscribers. The in-memory broker only
+// reaches subscribers in the same process; the MongoDB broker relays events
+// through a collection so every server instance sees them.
+package pubsub
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Event is something that happened in a group or to a user. Recipients are
+// the user IDs allowed to see it.
+type Event struct {
+	Type       string          `bson:"type"               json:"type"`
+	GroupID    string          `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	Recipients []string        `bson:"recipients"         json:"-"`
+	Data       json.RawMessage `bson:"data"               json:"data"`
+	CreatedAt  time.Time       `bson:"created_at"         json:"created_at"`
+}
+
+// Broker delivers published events to every current subscriber.
+type Broker interface {
+	Publish(event Event) error
+	// Subscribe returns a channel of events and a function that ends the
+	// subscription and closes the channel.
+	Subscribe() (<-chan Event, func())
+}
+
+// NewFromEnv picks the broker named by PUBSUB_BACKEND: "mongo" for the
+// MongoDB broker, anything else for the in-memory one.
+func NewFromEnv() Broker {
+	if os.Getenv("PUBSUB_BACKEND") == "mongo" {
+		return NewMongoBroker()
+	}
+	return NewMemoryBroker()
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"log"
+	"sync"
+)
+
+// subscriberBuffer is how many events a slow subscriber can fall behind by
+// before events to it are dropped.
+const subscriberBuffer = 64
+
+// MemoryBroker fans events out to subscribers in this process.
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[chan Event]struct{})}
+}
+
+func (b *MemoryBroker) Publish(event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			log.Println("Dropping", event.Type, "event for a slow subscriber")
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
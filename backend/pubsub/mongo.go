@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"splitwise/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// eventRetention is how long relayed events stay in the collection.
+const eventRetention = time.Hour
+
+// MongoBroker relays events through the "events" collection. Each instance
+// watches the collection with a change stream and hands what it sees to its
+// own subscribers, so it needs a replica set.
+type MongoBroker struct {
+	local *MemoryBroker
+}
+
+func NewMongoBroker() *MongoBroker {
+	b := &MongoBroker{local: NewMemoryBroker()}
+	b.ensureIndexes()
+	go b.watch()
+	return b
+}
+
+func (b *MongoBroker) col() *mongo.Collection {
+	return config.GetCollection("events")
+}
+
+// ensureIndexes expires old events so the collection doesn't grow forever.
+func (b *MongoBroker) ensureIndexes() {
+	_, err := b.col().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"created_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(eventRetention.Seconds())),
+	})
+	if err != nil {
+		log.Println("Failed to create events index:", err)
+	}
+}
+
+func (b *MongoBroker) Publish(event Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	_, err := b.col().InsertOne(context.Background(), event)
+	return err
+}
+
+func (b *MongoBroker) Subscribe() (<-chan Event, func()) {
+	return b.local.Subscribe()
+}
+
+// watch follows inserts into the events collection, reconnecting after
+// errors, and republishes them locally.
+func (b *MongoBroker) watch() {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	for {
+		stream, err := b.col().Watch(context.Background(), pipeline)
+		if err != nil {
+			log.Println("Event change stream failed to start:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for stream.Next(context.Background()) {
+			var change struct {
+				FullDocument Event `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				log.Println("Failed to decode event:", err)
+				continue
+			}
+			b.local.Publish(change.FullDocument)
+		}
+
+		log.Println("Event change stream closed:", stream.Err())
+		stream.Close(context.Background())
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccessTokenRepo struct{}
+
+func (r *AccessTokenRepo) col() *mongo.Collection {
+	return config.GetCollection("access_tokens")
+}
+
+func (r *AccessTokenRepo) Create(token *models.AccessToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+	_, err := r.col().InsertOne(context.Background(), token)
+	return err
+}
+
+func (r *AccessTokenRepo) GetByHash(hash string) (*models.AccessToken, error) {
+	var token models.AccessToken
+	err := r.col().FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *AccessTokenRepo) GetByUser(userID primitive.ObjectID) ([]models.AccessToken, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := r.col().Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []models.AccessToken
+	err = cursor.All(context.Background(), &tokens)
+	return tokens, err
+}
+
+func (r *AccessTokenRepo) CountByUser(userID primitive.ObjectID) (int64, error) {
+	return r.col().CountDocuments(context.Background(), bson.M{"user_id": userID})
+}
+
+// Delete removes one of the user's tokens, reporting whether it existed.
+func (r *AccessTokenRepo) Delete(id, userID primitive.ObjectID) (bool, error) {
+	res, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+func (r *AccessTokenRepo) DeleteByUser(userID primitive.ObjectID) error {
+	_, err := r.col().DeleteMany(context.Background(), bson.M{"user_id": userID})
+	return err
+}
+
+// TouchLastUsed records a use. Writes are skipped while the recorded use
+// is under a minute old, so busy scripts don't cost a write per request.
+func (r *AccessTokenRepo) TouchLastUsed(id primitive.ObjectID, ip string) error {
+	now := time.Now()
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-time.Minute)}},
+		}},
+		bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": ip}},
+	)
+	return err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BudgetRepo struct{}
+
+func (r *BudgetRepo) col() *mongo.Collection {
+	return config.GetCollection("budgets")
+}
+
+func (r *BudgetRepo) Create(budget *models.Budget) error {
+	budget.ID = primitive.NewObjectID()
+	budget.CreatedAt = time.Now()
+	if budget.AlertsSent == nil {
+		budget.AlertsSent = []int{}
+	}
+	_, err := r.col().InsertOne(context.Background(), budget)
+	return err
+}
+
+func (r *BudgetRepo) GetByID(id primitive.ObjectID) (*models.Budget, error) {
+	var budget models.Budget
+	err := r.col().FindOne(context.Background(), bson.M{"_id": id}).Decode(&budget)
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *BudgetRepo) GetByGroup(groupID primitive.ObjectID) ([]models.Budget, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{"group_id": groupID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var budgets []models.Budget
+	if err := cursor.All(context.Background(), &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// MarkAlertSent records that a threshold has been announced. It returns
+// false if it already was, so concurrent expenses only alert once.
+func (r *BudgetRepo) MarkAlertSent(id primitive.ObjectID, threshold int) (bool, error) {
+	res, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "alerts_sent": bson.M{"$ne": threshold}},
+		bson.M{"$push": bson.M{"alerts_sent": threshold}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ClearAlert forgets a threshold once spending drops back below it, so
+// crossing it again alerts again.
+func (r *BudgetRepo) ClearAlert(id primitive.ObjectID, threshold int) error {
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"alerts_sent": threshold}},
+	)
+	return err
+}
+
+func (r *BudgetRepo) DeleteBudget(id primitive.ObjectID) error {
+	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+func (r *BudgetRepo) DeleteByGroupID(groupID primitive.ObjectID) error {
+	_, err := r.col().DeleteMany(context.Background(), bson.M{"group_id": groupID})
+	return err
+}
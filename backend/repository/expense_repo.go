@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ExpenseRepo struct{}
@@ -16,10 +17,10 @@ type ExpenseRepo struct{}
 func (r *ExpenseRepo) col() *mongo.Collection {
 	return config.GetCollection("expenses")
 }
-func (r *ExpenseRepo) CreateExpense(expense *models.Expense) error {
+func (r *ExpenseRepo) CreateExpense(ctx context.Context, expense *models.Expense) error {
 	expense.ID = primitive.NewObjectID()
 	expense.CreatedAt = time.Now()
-	_, err := r.col().InsertOne(context.Background(), expense)
+	_, err := r.col().InsertOne(ctx, expense)
 	return err
 }
 func (r *ExpenseRepo) GetByGroup(groupID primitive.ObjectID) ([]models.Expense, error) {
@@ -34,8 +35,41 @@ func (r *ExpenseRepo) GetByGroup(groupID primitive.ObjectID) ([]models.Expense,
 	}
 	return expenses, nil
 }
-func (r *ExpenseRepo) DeleteExpense(id primitive.ObjectID) error {
-	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id})
+// GetByUser lists expenses the user paid for, has a share in or added.
+func (r *ExpenseRepo) GetByUser(userID primitive.ObjectID) ([]models.Expense, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"paid_by": userID},
+		{"splits.user_id": userID},
+		{"created_by": userID},
+	}}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.col().Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	var expenses []models.Expense
+	if err := cursor.All(context.Background(), &expenses); err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (r *ExpenseRepo) UpdateExpense(ctx context.Context, expense *models.Expense) error {
+	now := time.Now()
+	expense.UpdatedAt = &now
+	_, err := r.col().UpdateOne(ctx, bson.M{"_id": expense.ID}, bson.M{"$set": bson.M{
+		"paid_by":     expense.PaidBy,
+		"amount":      expense.Amount,
+		"description": expense.Description,
+		"category":    expense.Category,
+		"splits":      expense.Splits,
+		"updated_at":  now,
+	}})
+	return err
+}
+func (r *ExpenseRepo) DeleteExpense(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.col().DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
 func (r *ExpenseRepo) DeleteByGroupID(groupID primitive.ObjectID) error {
@@ -50,3 +84,52 @@ func (r *ExpenseRepo) GetByID(id primitive.ObjectID) (*models.Expense, error) {
 	}
 	return &expense, nil
 }
+
+// SumSpent totals the group's expenses created between from and to. An
+// empty category sums every expense.
+func (r *ExpenseRepo) SumSpent(groupID primitive.ObjectID, category string, from, to time.Time) (float64, error) {
+	match := bson.M{
+		"group_id":   groupID,
+		"created_at": bson.M{"$gte": from, "$lt": to},
+	}
+	if category != "" {
+		match["category"] = category
+	}
+	cursor, err := r.col().Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$amount"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var result []struct {
+		Total float64 `bson:"total"`
+	}
+	if err := cursor.All(context.Background(), &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Total, nil
+}
+
+// ReplaceUser rewrites every reference to from so it points at to.
+func (r *ExpenseRepo) ReplaceUser(ctx context.Context, from, to primitive.ObjectID) error {
+	for _, field := range []string{"paid_by", "created_by"} {
+		_, err := r.col().UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := r.col().UpdateMany(ctx,
+		bson.M{"splits.user_id": from},
+		bson.M{"$set": bson.M{"splits.$[split].user_id": to}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"split.user_id": from}},
+		}),
+	)
+	return err
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportRepo stores export jobs, and the archives themselves in the
+// "exports" GridFS bucket so they can exceed the 16MB document limit.
+type ExportRepo struct{}
+
+func (r *ExportRepo) col() *mongo.Collection {
+	return config.GetCollection("export_jobs")
+}
+
+func (r *ExportRepo) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(config.DB, options.GridFSBucket().SetName("exports"))
+}
+
+func (r *ExportRepo) Create(job *models.ExportJob) error {
+	job.ID = primitive.NewObjectID()
+	job.CreatedAt = time.Now()
+	_, err := r.col().InsertOne(context.Background(), job)
+	return err
+}
+
+func (r *ExportRepo) GetByID(id primitive.ObjectID) (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.col().FindOne(context.Background(), bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ExportRepo) GetByTokenHash(hash string) (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.col().FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetLatestByUser returns the user's most recent export job.
+func (r *ExportRepo) GetLatestByUser(userID primitive.ObjectID) (*models.ExportJob, error) {
+	var job models.ExportJob
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+	err := r.col().FindOne(context.Background(), bson.M{"user_id": userID}, opts).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SaveArchive uploads the finished archive and marks the job ready.
+func (r *ExportRepo) SaveArchive(job *models.ExportJob, data []byte) error {
+	bucket, err := r.bucket()
+	if err != nil {
+		return err
+	}
+	fileID, err := bucket.UploadFromStream("export-"+job.ID.Hex()+".zip", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	job.FileID = &fileID
+	job.Size = int64(len(data))
+	_, err = r.col().UpdateOne(context.Background(), bson.M{"_id": job.ID}, bson.M{"$set": bson.M{
+		"status":       models.ExportReady,
+		"file_id":      fileID,
+		"size":         job.Size,
+		"expires_at":   job.ExpiresAt,
+		"completed_at": job.CompletedAt,
+	}})
+	return err
+}
+
+// SetTokenHash replaces the job's download token.
+func (r *ExportRepo) SetTokenHash(id primitive.ObjectID, hash string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"token_hash": hash}})
+	return err
+}
+
+func (r *ExportRepo) MarkFailed(id primitive.ObjectID, reason string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":       models.ExportFailed,
+		"error":        reason,
+		"completed_at": time.Now(),
+	}})
+	return err
+}
+
+// OpenArchive streams a stored archive.
+func (r *ExportRepo) OpenArchive(fileID primitive.ObjectID) (io.ReadCloser, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	return bucket.OpenDownloadStream(fileID)
+}
+
+// GetExpired lists jobs whose download window has closed.
+func (r *ExportRepo) GetExpired(now time.Time) ([]models.ExportJob, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	var jobs []models.ExportJob
+	err = cursor.All(context.Background(), &jobs)
+	return jobs, err
+}
+
+// Delete removes a job along with its archive.
+func (r *ExportRepo) Delete(job *models.ExportJob) error {
+	if job.FileID != nil {
+		bucket, err := r.bucket()
+		if err != nil {
+			return err
+		}
+		if err := bucket.Delete(*job.FileID); err != nil && err != gridfs.ErrFileNotFound {
+			return err
+		}
+	}
+	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": job.ID})
+	return err
+}
+
+func (r *ExportRepo) DeleteByUser(userID primitive.ObjectID) error {
+	cursor, err := r.col().Find(context.Background(), bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	var jobs []models.ExportJob
+	if err := cursor.All(context.Background(), &jobs); err != nil {
+		return err
+	}
+	for i := range jobs {
+		if err := r.Delete(&jobs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -141,3 +141,38 @@ func (r *FriendRepo) GetSentRequests(userID primitive.ObjectID) ([]models.Friend
 	}
 	return friends, nil
 }
+
+// GetByUser lists every friendship and request involving the user,
+// whatever its status.
+func (r *FriendRepo) GetByUser(userID primitive.ObjectID) ([]models.Friend, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"requester": userID},
+		bson.M{"addressee": userID},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var friends []models.Friend
+	err = cursor.All(context.Background(), &friends)
+	return friends, err
+}
+
+// DeleteByUser removes every friendship and request involving the user.
+func (r *FriendRepo) DeleteByUser(userID primitive.ObjectID) error {
+	_, err := r.col().DeleteMany(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"requester": userID},
+		bson.M{"addressee": userID},
+	}})
+	return err
+}
+
+// ReplaceUser rewrites every reference to from so it points at to.
+func (r *FriendRepo) ReplaceUser(ctx context.Context, from, to primitive.ObjectID) error {
+	for _, field := range []string{"requester", "addressee"} {
+		_, err := r.col().UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
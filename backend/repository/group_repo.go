@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type GroupRepo struct{}
@@ -30,24 +31,147 @@ func (r *GroupRepo) GetByID(id primitive.ObjectID) (*models.Group, error) {
 	}
 	return &group, nil
 }
-func (r *GroupRepo) AddMember(groupID, userID primitive.ObjectID) error {
-	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": groupID}, bson.M{"$addToSet": bson.M{"members": userID}})
+func (r *GroupRepo) AddMember(groupID primitive.ObjectID, member models.GroupMember) error {
+	member.JoinedAt = time.Now()
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": groupID, "members.user_id": bson.M{"$ne": member.UserID}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$pull": bson.M{"former_members": bson.M{"user_id": member.UserID}},
+		},
+	)
 	return err
 }
 func (r *GroupRepo) RemoveMember(groupID, userID primitive.ObjectID) error {
-	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": groupID}, bson.M{"$pull": bson.M{"members": userID}})
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": groupID}, bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
 	return err
 }
+// RemoveMemberAsFormer removes a member and records them as a former member.
+func (r *GroupRepo) RemoveMemberAsFormer(groupID primitive.ObjectID, former models.FormerMember) error {
+	former.RemovedAt = time.Now()
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": groupID}, bson.M{
+		"$pull": bson.M{"members": bson.M{"user_id": former.UserID}},
+		"$push": bson.M{"former_members": former},
+	})
+	return err
+}
+func (r *GroupRepo) UpdateMemberRole(groupID, userID primitive.ObjectID, role string) error {
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": groupID, "members.user_id": userID},
+		bson.M{"$set": bson.M{"members.$.role": role}},
+	)
+	return err
+}
+// SetPendingOwner offers ownership to userID, or withdraws the offer when nil.
+func (r *GroupRepo) SetPendingOwner(groupID primitive.ObjectID, userID *primitive.ObjectID) error {
+	update := bson.M{"$unset": bson.M{"pending_owner": ""}}
+	if userID != nil {
+		update = bson.M{"$set": bson.M{"pending_owner": *userID}}
+	}
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": groupID}, update)
+	return err
+}
+
+// TransferOwnership makes to the owner and demotes from to admin. from may be
+// a user who is no longer a member, in which case only the promotion applies.
+func (r *GroupRepo) TransferOwnership(groupID, from, to primitive.ObjectID) error {
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"old.user_id": from, "old.role": models.RoleOwner},
+			bson.M{"new.user_id": to},
+		},
+	})
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": groupID, "members.user_id": to},
+		bson.M{
+			"$set": bson.M{
+				"members.$[old].role": models.RoleAdmin,
+				"members.$[new].role": models.RoleOwner,
+			},
+			"$unset": bson.M{"pending_owner": ""},
+		},
+		opts,
+	)
+	return err
+}
+
+// GetGroupsOwnedBy returns the groups in which the user holds the owner role.
+func (r *GroupRepo) GetGroupsOwnedBy(userID primitive.ObjectID) ([]models.Group, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{
+		"members": bson.M{"$elemMatch": bson.M{"user_id": userID, "role": models.RoleOwner}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	var groups []models.Group
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
 func (r *GroupRepo) UpdateGroupName(id primitive.ObjectID, name string) error {
 	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name}})
 	return err
 }
+func (r *GroupRepo) UpdateSettlementPolicy(id primitive.ObjectID, policy string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"settlement_policy": policy}})
+	return err
+}
+// BumpLedgerVersion marks the group's balances as changed.
+func (r *GroupRepo) BumpLedgerVersion(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"ledger_version": 1}})
+	return err
+}
+
+// AdvanceLedgerVersion bumps the ledger version only if it still equals
+// expected, reporting whether it did.
+func (r *GroupRepo) AdvanceLedgerVersion(ctx context.Context, id primitive.ObjectID, expected int64) (bool, error) {
+	filter := bson.M{"_id": id, "ledger_version": expected}
+	if expected == 0 {
+		// Groups created before ledger versioning have no field yet
+		filter = bson.M{"_id": id, "$or": []bson.M{
+			{"ledger_version": 0},
+			{"ledger_version": bson.M{"$exists": false}},
+		}}
+	}
+	res, err := r.col().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"ledger_version": 1}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+// SetDefaultSplit stores the group's default split, or clears it when nil.
+func (r *GroupRepo) SetDefaultSplit(id primitive.ObjectID, config *models.SplitConfig) error {
+	update := bson.M{"$unset": bson.M{"default_split": ""}}
+	if config != nil {
+		update = bson.M{"$set": bson.M{"default_split": config}}
+	}
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// SetArchived archives or unarchives a group.
+func (r *GroupRepo) SetArchived(id primitive.ObjectID, archived bool) error {
+	update := bson.M{"$set": bson.M{"archived": false}, "$unset": bson.M{"archived_at": ""}}
+	if archived {
+		update = bson.M{"$set": bson.M{"archived": true, "archived_at": time.Now()}}
+	}
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
 func (r *GroupRepo) DeleteGroup(id primitive.ObjectID) error {
 	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id})
 	return err
 }
-func (r *GroupRepo) GetGroupsByUserID(userID primitive.ObjectID) ([]models.Group, error) {
-	cursor, err := r.col().Find(context.Background(), bson.M{"members": userID})
+// GetGroupsByUserID lists the user's groups, leaving out archived ones unless
+// includeArchived is set.
+func (r *GroupRepo) GetGroupsByUserID(userID primitive.ObjectID, includeArchived bool) ([]models.Group, error) {
+	filter := bson.M{"members.user_id": userID}
+	if !includeArchived {
+		filter["archived"] = bson.M{"$ne": true}
+	}
+	cursor, err := r.col().Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
 	}
@@ -58,3 +182,37 @@ func (r *GroupRepo) GetGroupsByUserID(userID primitive.ObjectID) ([]models.Group
 	}
 	return groups, nil
 }
+
+// ReplaceUser rewrites every reference to from so it points at to. Where both
+// are already members of a group, from's membership is dropped instead.
+func (r *GroupRepo) ReplaceUser(ctx context.Context, from, to primitive.ObjectID) error {
+	_, err := r.col().UpdateMany(ctx,
+		bson.M{"members.user_id": bson.M{"$all": []primitive.ObjectID{from, to}}},
+		bson.M{"$pull": bson.M{"members": bson.M{"user_id": from}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	arrays := []string{"members", "former_members", "default_split.shares"}
+	for _, field := range arrays {
+		_, err := r.col().UpdateMany(ctx,
+			bson.M{field + ".user_id": from},
+			bson.M{"$set": bson.M{field + ".$[entry].user_id": to}},
+			options.Update().SetArrayFilters(options.ArrayFilters{
+				Filters: []interface{}{bson.M{"entry.user_id": from}},
+			}),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"created_by", "pending_owner"} {
+		_, err := r.col().UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"splitwise/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the API relies on. CreateOne is a no-op
+// when an index already exists, so this runs on every startup.
+func EnsureIndexes() {
+	indexes := map[string]mongo.IndexModel{
+		// Throttling only looks back a day
+		"login_attempts": {
+			Keys:    bson.M{"created_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60),
+		},
+		"login_challenges": {
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		"stream_tickets": {
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	for collection, index := range indexes {
+		if _, err := config.GetCollection(collection).Indexes().CreateOne(context.Background(), index); err != nil {
+			log.Printf("Failed to create index on %s: %v", collection, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InviteRepo struct{}
+
+func (r *InviteRepo) col() *mongo.Collection {
+	return config.GetCollection("group_invites")
+}
+
+// usableFilter matches invites that are unrevoked, unexpired and not used up.
+func usableFilter() bson.M {
+	return bson.M{
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": time.Now()},
+		"$or": []bson.M{
+			{"max_uses": 0},
+			{"$expr": bson.M{"$lt": []string{"$uses", "$max_uses"}}},
+		},
+	}
+}
+
+func (r *InviteRepo) Create(invite *models.GroupInvite) error {
+	invite.ID = primitive.NewObjectID()
+	invite.CreatedAt = time.Now()
+	_, err := r.col().InsertOne(context.Background(), invite)
+	return err
+}
+
+func (r *InviteRepo) GetByID(id primitive.ObjectID) (*models.GroupInvite, error) {
+	var invite models.GroupInvite
+	err := r.col().FindOne(context.Background(), bson.M{"_id": id}).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepo) GetByToken(token string) (*models.GroupInvite, error) {
+	var invite models.GroupInvite
+	err := r.col().FindOne(context.Background(), bson.M{"token": token}).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// GetActiveByGroup returns the group's invites that can still be redeemed.
+func (r *InviteRepo) GetActiveByGroup(groupID primitive.ObjectID) ([]models.GroupInvite, error) {
+	filter := usableFilter()
+	filter["group_id"] = groupID
+	cursor, err := r.col().Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var invites []models.GroupInvite
+	if err := cursor.All(context.Background(), &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Redeem atomically uses up one slot of a usable invite. It returns
+// mongo.ErrNoDocuments if the invite is missing, revoked, expired or used up.
+func (r *InviteRepo) Redeem(token string) (*models.GroupInvite, error) {
+	filter := usableFilter()
+	filter["token"] = token
+	var invite models.GroupInvite
+	err := r.col().FindOneAndUpdate(context.Background(),
+		filter,
+		bson.M{"$inc": bson.M{"uses": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepo) Revoke(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+func (r *InviteRepo) DeleteByGroupID(groupID primitive.ObjectID) error {
+	_, err := r.col().DeleteMany(context.Background(), bson.M{"group_id": groupID})
+	return err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginAttemptRepo struct{}
+
+func (r *LoginAttemptRepo) col() *mongo.Collection {
+	return config.GetCollection("login_attempts")
+}
+
+func (r *LoginAttemptRepo) lockoutCol() *mongo.Collection {
+	return config.GetCollection("lockout_events")
+}
+
+func (r *LoginAttemptRepo) Record(kind, email, ip string) error {
+	_, err := r.col().InsertOne(context.Background(), models.LoginAttempt{
+		ID:        primitive.NewObjectID(),
+		Kind:      kind,
+		Email:     email,
+		IP:        ip,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+func (r *LoginAttemptRepo) CountByEmailSince(kind, email string, since time.Time) (int64, error) {
+	return r.col().CountDocuments(context.Background(), bson.M{
+		"kind": kind, "email": email, "created_at": bson.M{"$gte": since},
+	})
+}
+
+func (r *LoginAttemptRepo) CountByIPSince(kind, ip string, since time.Time) (int64, error) {
+	return r.col().CountDocuments(context.Background(), bson.M{
+		"kind": kind, "ip": ip, "created_at": bson.M{"$gte": since},
+	})
+}
+
+// LastByEmail returns when the latest attempt of kind for email was made.
+func (r *LoginAttemptRepo) LastByEmail(kind, email string) (time.Time, error) {
+	var attempt models.LoginAttempt
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+	err := r.col().FindOne(context.Background(), bson.M{"kind": kind, "email": email}, opts).Decode(&attempt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return attempt.CreatedAt, nil
+}
+
+func (r *LoginAttemptRepo) ClearByEmail(kind, email string) error {
+	_, err := r.col().DeleteMany(context.Background(), bson.M{"kind": kind, "email": email})
+	return err
+}
+
+func (r *LoginAttemptRepo) RecordLockout(event *models.LockoutEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+	_, err := r.lockoutCol().InsertOne(context.Background(), event)
+	return err
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunMigrations upgrades documents written by older versions of the API.
+// Every migration must be safe to run on each startup.
+func RunMigrations() {
+	if err := migrateGroupMembers(); err != nil {
+		log.Println("Migration of group members failed:", err)
+	}
+	if err := migrateEmailVerified(); err != nil {
+		log.Println("Migration of email verification failed:", err)
+	}
+}
+
+// migrateEmailVerified treats accounts created before email verification
+// existed as verified.
+func migrateEmailVerified() error {
+	_, err := config.GetCollection("users").UpdateMany(context.Background(),
+		bson.M{"email_verified": bson.M{"$exists": false}, "placeholder": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	return err
+}
+
+// migrateGroupMembers turns the old members list of user IDs into member
+// records with roles: the creator becomes the owner, everyone else a member.
+func migrateGroupMembers() error {
+	col := config.GetCollection("groups")
+	cursor, err := col.Find(context.Background(), bson.M{"members.0": bson.M{"$type": "objectId"}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var legacy struct {
+			ID        primitive.ObjectID   `bson:"_id"`
+			CreatedBy primitive.ObjectID   `bson:"created_by"`
+			Members   []primitive.ObjectID `bson:"members"`
+			CreatedAt time.Time            `bson:"created_at"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			return err
+		}
+
+		members := make([]models.GroupMember, 0, len(legacy.Members))
+		for _, userID := range legacy.Members {
+			role := models.RoleMember
+			if userID == legacy.CreatedBy {
+				role = models.RoleOwner
+			}
+			members = append(members, models.GroupMember{
+				UserID:   userID,
+				Role:     role,
+				JoinedAt: legacy.CreatedAt,
+			})
+		}
+
+		_, err := col.UpdateOne(context.Background(),
+			bson.M{"_id": legacy.ID},
+			bson.M{"$set": bson.M{"members": members}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
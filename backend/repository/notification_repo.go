@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepo struct{}
+
+func (r *NotificationRepo) col() *mongo.Collection {
+	return config.GetCollection("notifications")
+}
+
+func (r *NotificationRepo) prefs() *mongo.Collection {
+	return config.GetCollection("notification_preferences")
+}
+
+func (r *NotificationRepo) CreateMany(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(notifications))
+	for i := range notifications {
+		notifications[i].ID = primitive.NewObjectID()
+		notifications[i].CreatedAt = time.Now()
+		docs = append(docs, notifications[i])
+	}
+	_, err := r.col().InsertMany(context.Background(), docs)
+	return err
+}
+
+// GetByUser returns the user's most recent notifications, newest first.
+func (r *NotificationRepo) GetByUser(userID primitive.ObjectID, unreadOnly bool, limit int64) ([]models.Notification, error) {
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	cursor, err := r.col().Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var notifications []models.Notification
+	if err := cursor.All(context.Background(), &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepo) CountUnread(userID primitive.ObjectID) (int64, error) {
+	return r.col().CountDocuments(context.Background(), bson.M{"user_id": userID, "read": false})
+}
+
+// MarkRead marks one of the user's notifications as read. It returns
+// mongo.ErrNoDocuments if the notification isn't theirs.
+func (r *NotificationRepo) MarkRead(id, userID primitive.ObjectID) error {
+	res, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *NotificationRepo) MarkAllRead(userID primitive.ObjectID) error {
+	_, err := r.col().UpdateMany(context.Background(),
+		bson.M{"user_id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	return err
+}
+
+// GetPreferences returns the user's preferences, or empty ones if they have
+// never changed them.
+func (r *NotificationRepo) GetPreferences(userID primitive.ObjectID) (*models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{UserID: userID, Muted: []string{}, Email: []string{}}
+	err := r.prefs().FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&prefs)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (r *NotificationRepo) SetPreferences(prefs *models.NotificationPreferences) error {
+	_, err := r.prefs().UpdateOne(context.Background(),
+		bson.M{"user_id": prefs.UserID},
+		bson.M{"$set": bson.M{"muted": prefs.Muted, "email": prefs.Email}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// DeleteByUser removes the user's notifications and preferences.
+func (r *NotificationRepo) DeleteByUser(userID primitive.ObjectID) error {
+	if _, err := r.col().DeleteMany(context.Background(), bson.M{"user_id": userID}); err != nil {
+		return err
+	}
+	_, err := r.prefs().DeleteOne(context.Background(), bson.M{"user_id": userID})
+	return err
+}
+
+// ReplaceUser moves notifications addressed to from over to to.
+func (r *NotificationRepo) ReplaceUser(ctx context.Context, from, to primitive.ObjectID) error {
+	for _, field := range []string{"user_id", "actor_id"} {
+		_, err := r.col().UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionRepo struct{}
+
+func (r *SessionRepo) col() *mongo.Collection {
+	return config.GetCollection("sessions")
+}
+
+func (r *SessionRepo) Create(session *models.Session) error {
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+	_, err := r.col().InsertOne(context.Background(), session)
+	return err
+}
+
+// GetByRefreshHash finds the session that currently owns a refresh token.
+func (r *SessionRepo) GetByRefreshHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.col().FindOne(context.Background(), bson.M{"refresh_hash": hash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByPreviousHash finds the session a refresh token was rotated out of.
+func (r *SessionRepo) GetByPreviousHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.col().FindOne(context.Background(), bson.M{"previous_hash": hash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate swaps the session's refresh token, but only if oldHash is still
+// current, so two concurrent refreshes can't both succeed.
+func (r *SessionRepo) Rotate(id primitive.ObjectID, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	res, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "refresh_hash": oldHash, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"refresh_hash":  newHash,
+			"previous_hash": oldHash,
+			"expires_at":    expiresAt,
+			"last_used_at":  time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// IsActive reports whether the session exists, belongs to userID and has
+// been neither revoked nor left to expire.
+func (r *SessionRepo) IsActive(id, userID primitive.ObjectID) (bool, error) {
+	count, err := r.col().CountDocuments(context.Background(), bson.M{
+		"_id":        id,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	return count == 1, err
+}
+
+func (r *SessionRepo) Revoke(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeAllForUser ends every session the user has, apart from except when
+// it is set.
+func (r *SessionRepo) RevokeAllForUser(userID primitive.ObjectID, except *primitive.ObjectID) error {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	if except != nil {
+		filter["_id"] = bson.M{"$ne": *except}
+	}
+	_, err := r.col().UpdateMany(context.Background(), filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+func (r *SessionRepo) GetByUser(userID primitive.ObjectID) ([]models.Session, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	var sessions []models.Session
+	err = cursor.All(context.Background(), &sessions)
+	return sessions, err
+}
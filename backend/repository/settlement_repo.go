@@ -16,31 +16,69 @@ type SettlementRepo struct{}
 func (r *SettlementRepo) col() *mongo.Collection {
 	return config.GetCollection("settlements")
 }
-func (r *SettlementRepo) CreateSettlement(settlement *models.Settlement) error {
+func (r *SettlementRepo) CreateSettlement(ctx context.Context, settlement *models.Settlement) error {
 	settlement.ID = primitive.NewObjectID()
 	settlement.CreatedAt = time.Now()
-	_, err := r.col().InsertOne(context.Background(), settlement)
+	_, err := r.col().InsertOne(ctx, settlement)
 	return err
 }
-func (r *SettlementRepo) GetByGroup(groupID primitive.ObjectID) ([]models.Settlement, error) {
-	cursor, err := r.col().Find(context.Background(), bson.M{"group_id": groupID})
+// CreateMany inserts several settlements, typically inside a transaction.
+func (r *SettlementRepo) CreateMany(ctx context.Context, settlements []models.Settlement) error {
+	docs := make([]interface{}, len(settlements))
+	for i := range settlements {
+		settlements[i].ID = primitive.NewObjectID()
+		settlements[i].CreatedAt = time.Now()
+		docs[i] = settlements[i]
+	}
+	_, err := r.col().InsertMany(ctx, docs)
+	return err
+}
+// GetByGroup lists a group's settlements, optionally only those paid with method.
+func (r *SettlementRepo) GetByGroup(groupID primitive.ObjectID, method string) ([]models.Settlement, error) {
+	filter := bson.M{"group_id": groupID}
+	if method != "" {
+		filter["method"] = method
+	}
+	return r.find(filter)
+}
+func (r *SettlementRepo) GetByID(id primitive.ObjectID) (*models.Settlement, error) {
+	var settlement models.Settlement
+	err := r.col().FindOne(context.Background(), bson.M{"_id": id}).Decode(&settlement)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
-	var settlements []models.Settlement
-	if err := cursor.All(context.Background(), &settlements); err != nil {
-		return nil, err
+	return &settlement, nil
+}
+
+// GetConfirmedByGroup returns the settlements that count towards balances,
+// including legacy records that have no status.
+func (r *SettlementRepo) GetConfirmedByGroup(groupID primitive.ObjectID) ([]models.Settlement, error) {
+	return r.find(bson.M{"group_id": groupID, "status": bson.M{"$nin": []string{"pending", "rejected"}}})
+}
+
+// GetPendingByGroup returns settlements still awaiting the payee's confirmation.
+func (r *SettlementRepo) GetPendingByGroup(groupID primitive.ObjectID) ([]models.Settlement, error) {
+	return r.find(bson.M{"group_id": groupID, "status": "pending"})
+}
+
+// UpdateStatus records the payee's response to a pending settlement. It
+// returns mongo.ErrNoDocuments if the settlement is no longer pending.
+func (r *SettlementRepo) UpdateStatus(ctx context.Context, id primitive.ObjectID, status, reason string) error {
+	set := bson.M{"status": status, "responded_at": time.Now()}
+	if reason != "" {
+		set["reject_reason"] = reason
 	}
-	return settlements, nil
+	res, err := r.col().UpdateOne(ctx, bson.M{"_id": id, "status": "pending"}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
-func (r *SettlementRepo) GetByUser(id primitive.ObjectID) ([]models.Settlement, error) {
-	cursor, err := r.col().Find(context.Background(),
-		bson.M{"$or": []bson.M{
-			{"paid_by": id},
-			{"paid_to": id},
-		}},
-	)
+func (r *SettlementRepo) find(filter bson.M) ([]models.Settlement, error) {
+	cursor, err := r.col().Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
 	}
@@ -51,11 +89,34 @@ func (r *SettlementRepo) GetByUser(id primitive.ObjectID) ([]models.Settlement,
 	}
 	return settlements, nil
 }
+// GetByUser lists settlements a user paid or received, optionally only those
+// paid with method.
+func (r *SettlementRepo) GetByUser(id primitive.ObjectID, method string) ([]models.Settlement, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"paid_by": id},
+		{"paid_to": id},
+	}}
+	if method != "" {
+		filter["method"] = method
+	}
+	return r.find(filter)
+}
 func (r *SettlementRepo) DeleteByGroupID(groupID primitive.ObjectID) error {
 	_, err := r.col().DeleteMany(context.Background(), bson.M{"group_id": groupID})
 	return err
 }
-func (r *SettlementRepo) DeleteSettlement(id primitive.ObjectID) error {
-	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id})
+func (r *SettlementRepo) DeleteSettlement(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.col().DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// ReplaceUser rewrites every reference to from so it points at to.
+func (r *SettlementRepo) ReplaceUser(ctx context.Context, from, to primitive.ObjectID) error {
+	for _, field := range []string{"paid_by", "paid_to"} {
+		_, err := r.col().UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
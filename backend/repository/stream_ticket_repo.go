@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type StreamTicketRepo struct{}
+
+func (r *StreamTicketRepo) col() *mongo.Collection {
+	return config.GetCollection("stream_tickets")
+}
+
+func (r *StreamTicketRepo) Create(ticket *models.StreamTicket) error {
+	ticket.ID = primitive.NewObjectID()
+	ticket.CreatedAt = time.Now()
+	_, err := r.col().InsertOne(context.Background(), ticket)
+	return err
+}
+
+// Consume deletes and returns the unexpired ticket with hash, so each ticket
+// opens at most one stream.
+func (r *StreamTicketRepo) Consume(hash string) (*models.StreamTicket, error) {
+	var ticket models.StreamTicket
+	err := r.col().FindOneAndDelete(context.Background(), bson.M{
+		"ticket_hash": hash,
+		"expires_at":  bson.M{"$gt": time.Now()},
+	}).Decode(&ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
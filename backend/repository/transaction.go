@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"splitwise/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn inside a MongoDB transaction, retrying on transient
+// errors. Transactions need a replica set deployment (Atlas clusters are).
+func WithTransaction(fn func(ctx mongo.SessionContext) error) error {
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepo struct{}
@@ -20,15 +21,64 @@ func (r *UserRepo) col() *mongo.Collection {
 func (r *UserRepo) resetCol() *mongo.Collection {
 	return config.GetCollection("password_resets")
 }
+
+func (r *UserRepo) verificationCol() *mongo.Collection {
+	return config.GetCollection("email_verifications")
+}
+
+func (r *UserRepo) challengeCol() *mongo.Collection {
+	return config.GetCollection("login_challenges")
+}
 func (r *UserRepo) CreateUser(user *models.User) error {
 	user.ID = primitive.NewObjectID()
 	user.CreatedAt = time.Now()
 	_, err := r.col().InsertOne(context.Background(), user)
 	return err
 }
+// GetByEmail prefers a registered account over a placeholder with the same
+// email, and a verified account over ones that never verified it.
 func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.col().FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
+	opts := options.FindOne().SetSort(emailPreference)
+	err := r.col().FindOne(context.Background(), bson.M{"email": email}, opts).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetAccountsByEmail lists the registered accounts using email, in the same
+// order of preference as GetByEmail. Until one of them verifies the address
+// there can be several.
+func (r *UserRepo) GetAccountsByEmail(email string) ([]models.User, error) {
+	opts := options.Find().SetSort(emailPreference)
+	cursor, err := r.col().Find(context.Background(), bson.M{
+		"email":       email,
+		"placeholder": bson.M{"$ne": true},
+		"deleted":     bson.M{"$ne": true},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	var users []models.User
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// emailPreference orders accounts sharing an email: registered before
+// placeholder, verified before unverified, then oldest first.
+var emailPreference = bson.D{
+	{Key: "placeholder", Value: 1},
+	{Key: "email_verified", Value: -1},
+	{Key: "created_at", Value: 1},
+}
+
+func (r *UserRepo) GetPlaceholderByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.col().FindOne(context.Background(), bson.M{"email": email, "placeholder": true}).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -47,9 +97,19 @@ func (r *UserRepo) UpdateUser(id primitive.ObjectID, name string) error {
 	return err
 }
 
+func (r *UserRepo) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.col().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// GetAll lists verified accounts and placeholders; accounts that haven't
+// verified their email stay hidden.
 func (r *UserRepo) GetAll() ([]models.User, error) {
 	var users []models.User
-	cursor, err := r.col().Find(context.Background(), bson.D{})
+	cursor, err := r.col().Find(context.Background(), bson.M{"$or": []bson.M{
+		{"email_verified": true},
+		{"placeholder": true},
+	}})
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +125,13 @@ func (r *UserRepo) UpdatePassword(id primitive.ObjectID, hashedPassword string)
 	return err
 }
 
+// UpdateEmail moves the account to a new, already verified address.
+func (r *UserRepo) UpdateEmail(id primitive.ObjectID, email string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"email": email, "email_verified": true}})
+	return err
+}
+
 func (r *UserRepo) CreatePasswordReset(reset *models.PasswordReset) error {
 	reset.ID = primitive.NewObjectID()
 	reset.CreatedAt = time.Now()
@@ -87,3 +154,228 @@ func (r *UserRepo) DeletePasswordReset(id primitive.ObjectID) error {
 	_, err := r.resetCol().DeleteOne(context.Background(), bson.M{"_id": id})
 	return err
 }
+
+func (r *UserRepo) SetEmailVerified(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"email_verified": true}})
+	return err
+}
+
+// CreateEmailVerification stores a new token. Earlier tokens stay valid
+// until they expire so an older email still works after a resend.
+func (r *UserRepo) CreateEmailVerification(verification *models.EmailVerification) error {
+	verification.ID = primitive.NewObjectID()
+	verification.CreatedAt = time.Now()
+	_, err := r.verificationCol().InsertOne(context.Background(), verification)
+	return err
+}
+
+func (r *UserRepo) GetEmailVerificationByToken(token string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.verificationCol().FindOne(context.Background(), bson.M{"token": token}).Decode(&verification)
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// GetLatestEmailVerification returns the user's most recent token, for rate
+// limiting resends.
+func (r *UserRepo) GetLatestEmailVerification(userID primitive.ObjectID) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+	err := r.verificationCol().FindOne(context.Background(), bson.M{"user_id": userID}, opts).Decode(&verification)
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (r *UserRepo) CountEmailVerificationsSince(userID primitive.ObjectID, since time.Time) (int64, error) {
+	return r.verificationCol().CountDocuments(context.Background(), bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$gte": since},
+	})
+}
+
+func (r *UserRepo) DeleteEmailVerifications(userID primitive.ObjectID) error {
+	_, err := r.verificationCol().DeleteMany(context.Background(), bson.M{"user_id": userID})
+	return err
+}
+
+// SetPendingTOTP stores a secret for an enrollment that hasn't been
+// confirmed yet, replacing any earlier attempt.
+func (r *UserRepo) SetPendingTOTP(id primitive.ObjectID, secret string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"totp_pending_secret": secret}})
+	return err
+}
+
+// EnableTOTP promotes the pending secret and stores the recovery code hashes.
+// step is the code used to confirm, so it can't be replayed at login.
+func (r *UserRepo) EnableTOTP(id primitive.ObjectID, secret string, step int64, recoveryHashes []string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"two_factor_enabled": true,
+			"totp_secret":        secret,
+			"totp_last_step":     step,
+			"recovery_codes":     recoveryHashes,
+		},
+		"$unset": bson.M{"totp_pending_secret": ""},
+	})
+	return err
+}
+
+func (r *UserRepo) DisableTOTP(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"two_factor_enabled": false},
+		"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "totp_last_step": "", "recovery_codes": ""},
+	})
+	return err
+}
+
+func (r *UserRepo) SetRecoveryCodes(id primitive.ObjectID, recoveryHashes []string) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"recovery_codes": recoveryHashes}})
+	return err
+}
+
+// UseTOTPStep records that a code for step was accepted. It fails if that
+// step or a later one was already used, so each code works only once.
+func (r *UserRepo) UseTOTPStep(id primitive.ObjectID, step int64) (bool, error) {
+	res, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes a recovery code hash, reporting whether it was
+// there to remove.
+func (r *UserRepo) UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error) {
+	res, err := r.col().UpdateOne(context.Background(),
+		bson.M{"_id": id, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *UserRepo) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	challenge.ID = primitive.NewObjectID()
+	challenge.CreatedAt = time.Now()
+	_, err := r.challengeCol().InsertOne(context.Background(), challenge)
+	return err
+}
+
+func (r *UserRepo) GetLoginChallengeByHash(hash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := r.challengeCol().FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ClaimChallengeAttempt uses up one attempt at the unexpired challenge with
+// hash, if it has fewer than maxAttempts, and returns it. Claiming before the
+// code is checked keeps concurrent guesses within the limit.
+func (r *UserRepo) ClaimChallengeAttempt(hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := r.challengeCol().FindOneAndUpdate(context.Background(),
+		bson.M{
+			"token_hash": hash,
+			"attempts":   bson.M{"$lt": maxAttempts},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+	).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *UserRepo) DeleteLoginChallenge(id primitive.ObjectID) error {
+	_, err := r.challengeCol().DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+func (r *UserRepo) Lock(id primitive.ObjectID, until time.Time) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"locked_until": until}})
+	return err
+}
+
+func (r *UserRepo) Unlock(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$unset": bson.M{"locked_until": ""}})
+	return err
+}
+
+// MarkDeletionStarted records that the account is being deleted.
+func (r *UserRepo) MarkDeletionStarted(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"deletion_started_at": time.Now()}})
+	return err
+}
+
+// GetStalledDeletions lists accounts whose deletion started before since
+// but never finished.
+func (r *UserRepo) GetStalledDeletions(since time.Time) ([]models.User, error) {
+	cursor, err := r.col().Find(context.Background(), bson.M{
+		"deletion_started_at": bson.M{"$lt": since},
+		"deleted":             bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	var users []models.User
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Anonymize strips a deleted account of its personal data and credentials.
+// The record stays so the ledger can still refer to it.
+func (r *UserRepo) Anonymize(id primitive.ObjectID) error {
+	_, err := r.col().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"name":               "Deleted user",
+			"email":              "deleted-" + id.Hex() + "@deleted.invalid",
+			"password":           "",
+			"email_verified":     false,
+			"two_factor_enabled": false,
+			"deleted":            true,
+			"deleted_at":         time.Now(),
+		},
+		"$unset": bson.M{
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_last_step":      "",
+			"recovery_codes":      "",
+			"locked_until":        "",
+		},
+	})
+	return err
+}
+
+// DeleteTokens removes the user's outstanding reset, verification and
+// login challenge tokens.
+func (r *UserRepo) DeleteTokens(id primitive.ObjectID) error {
+	for _, col := range []*mongo.Collection{r.resetCol(), r.verificationCol(), r.challengeCol()} {
+		if _, err := col.DeleteMany(context.Background(), bson.M{"user_id": id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"splitwise/config"
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookRepo struct{}
+
+func (r *WebhookRepo) col() *mongo.Collection {
+	return config.GetCollection("webhooks")
+}
+
+func (r *WebhookRepo) deliveries() *mongo.Collection {
+	return config.GetCollection("webhook_deliveries")
+}
+
+func (r *WebhookRepo) Create(hook *models.Webhook) error {
+	hook.ID = primitive.NewObjectID()
+	hook.CreatedAt = time.Now()
+	_, err := r.col().InsertOne(context.Background(), hook)
+	return err
+}
+
+func (r *WebhookRepo) GetByID(id primitive.ObjectID) (*models.Webhook, error) {
+	var hook models.Webhook
+	err := r.col().FindOne(context.Background(), bson.M{"_id": id}).Decode(&hook)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *WebhookRepo) GetByOwner(ownerID primitive.ObjectID) ([]models.Webhook, error) {
+	return r.find(bson.M{"owner_id": ownerID})
+}
+
+// FindMatching returns the webhooks subscribed to an event of type kind in
+// groupID (if any) that is visible to recipients.
+func (r *WebhookRepo) FindMatching(kind string, groupID *primitive.ObjectID, recipients []primitive.ObjectID) ([]models.Webhook, error) {
+	scopes := []bson.M{{"group_id": bson.M{"$exists": false}, "owner_id": bson.M{"$in": recipients}}}
+	if groupID != nil {
+		scopes = append(scopes, bson.M{"group_id": *groupID})
+	}
+	return r.find(bson.M{
+		"$or": scopes,
+		"$and": []bson.M{{"$or": []bson.M{
+			{"events": bson.M{"$size": 0}},
+			{"events": kind},
+		}}},
+	})
+}
+
+func (r *WebhookRepo) find(filter bson.M) ([]models.Webhook, error) {
+	cursor, err := r.col().Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var hooks []models.Webhook
+	if err := cursor.All(context.Background(), &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// Delete removes a webhook along with its delivery log.
+func (r *WebhookRepo) Delete(id primitive.ObjectID) error {
+	if _, err := r.deliveries().DeleteMany(context.Background(), bson.M{"webhook_id": id}); err != nil {
+		return err
+	}
+	_, err := r.col().DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+func (r *WebhookRepo) DeleteByOwner(ownerID primitive.ObjectID) error {
+	hooks, err := r.GetByOwner(ownerID)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := r.Delete(hook.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *WebhookRepo) DeleteByGroupID(groupID primitive.ObjectID) error {
+	hooks, err := r.find(bson.M{"group_id": groupID})
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := r.Delete(hook.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *WebhookRepo) CreateDelivery(delivery *models.WebhookDelivery) error {
+	delivery.ID = primitive.NewObjectID()
+	delivery.CreatedAt = time.Now()
+	_, err := r.deliveries().InsertOne(context.Background(), delivery)
+	return err
+}
+
+// ClaimDue picks a pending delivery whose next attempt is due and pushes its
+// next attempt back by lease, so other workers leave it alone while it is
+// being sent. It returns mongo.ErrNoDocuments when nothing is due.
+func (r *WebhookRepo) ClaimDue(lease time.Duration) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	var delivery models.WebhookDelivery
+	err := r.deliveries().FindOneAndUpdate(context.Background(),
+		bson.M{"status": "pending", "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.M{"next_attempt_at": 1}).
+			SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt.
+func (r *WebhookRepo) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	_, err := r.deliveries().UpdateOne(context.Background(), bson.M{"_id": delivery.ID}, bson.M{"$set": bson.M{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"status_code":     delivery.StatusCode,
+		"last_error":      delivery.LastError,
+		"delivered_at":    delivery.DeliveredAt,
+	}})
+	return err
+}
+
+// GetDeliveries returns the webhook's most recent deliveries, newest first.
+func (r *WebhookRepo) GetDeliveries(webhookID primitive.ObjectID, limit int64) ([]models.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	cursor, err := r.deliveries().Find(context.Background(), bson.M{"webhook_id": webhookID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var deliveries []models.WebhookDelivery
+	if err := cursor.All(context.Background(), &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"splitwise/handlers"
+	"splitwise/mailer"
 	"splitwise/middleware"
+	"splitwise/pubsub"
 	"splitwise/repository"
 	"splitwise/services"
 
@@ -19,40 +23,136 @@ func SetupRouter() http.Handler {
 	expenseRepo := &repository.ExpenseRepo{}
 	settlementRepo := &repository.SettlementRepo{}
 	friendRepo := &repository.FriendRepo{}
+	inviteRepo := &repository.InviteRepo{}
+	budgetRepo := &repository.BudgetRepo{}
+	notificationRepo := &repository.NotificationRepo{}
+	webhookRepo := &repository.WebhookRepo{}
+	sessionRepo := &repository.SessionRepo{}
+	accessTokenRepo := &repository.AccessTokenRepo{}
+	loginAttemptRepo := &repository.LoginAttemptRepo{}
+	exportRepo := &repository.ExportRepo{}
+	streamTicketRepo := &repository.StreamTicketRepo{}
 
 	// Services
-	userSvc := &services.UserService{Repo: userRepo}
+	eventBus := &services.EventBus{Broker: pubsub.NewFromEnv()}
+	mail := mailer.NewFromEnv()
+	sessionSvc := &services.SessionService{Repo: sessionRepo}
+	middleware.ValidateSession = sessionSvc.Validate
+	accessTokenSvc := &services.AccessTokenService{Repo: accessTokenRepo}
+	middleware.AuthenticateAccessToken = accessTokenSvc.Authenticate
+	streamTicketSvc := &services.StreamTicketService{Repo: streamTicketRepo}
+	middleware.RedeemStreamTicket = streamTicketSvc.Redeem
+	notificationSvc := &services.NotificationService{
+		Repo:     notificationRepo,
+		UserRepo: userRepo,
+		Mailer:   mail,
+	}
+	balanceSvc := &services.BalanceService{
+		ExpenseRepo:    expenseRepo,
+		GroupRepo:      groupRepo,
+		SettlementRepo: settlementRepo,
+	}
 	groupSvc := &services.GroupService{
 		Repo:           groupRepo,
 		UserRepo:       userRepo,
 		ExpenseRepo:    expenseRepo,
 		SettlementRepo: settlementRepo,
+		InviteRepo:     inviteRepo,
+		BudgetRepo:     budgetRepo,
+		WebhookRepo:    webhookRepo,
+		BalanceSvc:     balanceSvc,
+		Notifier:       notificationSvc,
+		Events:         eventBus,
 	}
-	balanceSvc := &services.BalanceService{
-		ExpenseRepo:    expenseRepo,
+	userSvc := &services.UserService{
+		Repo:           userRepo,
 		GroupRepo:      groupRepo,
+		ExpenseRepo:    expenseRepo,
 		SettlementRepo: settlementRepo,
+		FriendRepo:     friendRepo,
+		NotifyRepo:     notificationRepo,
+		TokenRepo:      accessTokenRepo,
+		WebhookRepo:    webhookRepo,
+		ExportRepo:     exportRepo,
+		Attempts:       loginAttemptRepo,
+		GroupSvc:       groupSvc,
+		Sessions:       sessionSvc,
+		Mailer:         mail,
+	}
+	budgetSvc := &services.BudgetService{
+		Repo:        budgetRepo,
+		GroupRepo:   groupRepo,
+		ExpenseRepo: expenseRepo,
+		Notifier:    notificationSvc,
 	}
 	expenseSvc := &services.ExpenseService{
 		Repo:      expenseRepo,
 		GroupRepo: groupRepo,
+		BudgetSvc: budgetSvc,
+		Notifier:  notificationSvc,
+		Events:    eventBus,
 	}
 	settlementSvc := &services.SettlementService{
 		Repo:       settlementRepo,
+		GroupRepo:  groupRepo,
+		UserRepo:   userRepo,
 		BalanceSvc: balanceSvc,
+		Notifier:   notificationSvc,
+		Events:     eventBus,
 	}
 	friendSvc := &services.FriendService{
 		Repo:     friendRepo,
 		UserRepo: userRepo,
+		Notifier: notificationSvc,
+	}
+	webhookWorker := &services.WebhookWorker{
+		Repo:   webhookRepo,
+		Client: services.NewWebhookClient(10 * time.Second),
+	}
+	webhookSvc := &services.WebhookService{
+		Repo:      webhookRepo,
+		GroupRepo: groupRepo,
+		Worker:    webhookWorker,
+	}
+	eventBus.OnPublish(webhookSvc.HandleEvent)
+	go webhookWorker.Run(context.Background())
+
+	exportSvc := &services.ExportService{
+		Repo:           exportRepo,
+		UserRepo:       userRepo,
+		GroupRepo:      groupRepo,
+		ExpenseRepo:    expenseRepo,
+		SettlementRepo: settlementRepo,
+		FriendRepo:     friendRepo,
+		NotifyRepo:     notificationRepo,
+		SessionRepo:    sessionRepo,
+		TokenRepo:      accessTokenRepo,
+	}
+	go exportSvc.RunCleanup(context.Background())
+	go userSvc.RunPendingDeletions(context.Background())
+
+	inviteSvc := &services.InviteService{
+		Repo:      inviteRepo,
+		GroupRepo: groupRepo,
+		UserRepo:  userRepo,
+		GroupSvc:  groupSvc,
+		Events:    eventBus,
 	}
 
 	// Handlers
-	userHandler := &handlers.UserHandler{Service: userSvc}
+	userHandler := &handlers.UserHandler{Service: userSvc, Sessions: sessionSvc}
 	groupHandler := &handlers.GroupHandler{Service: groupSvc}
 	expenseHandler := &handlers.ExpenseHandler{Service: expenseSvc}
 	balanceHandler := &handlers.BalanceHandler{Service: balanceSvc}
 	settlementHandler := &handlers.SettlementHandler{Service: settlementSvc}
 	friendHandler := &handlers.FriendHandler{Service: friendSvc}
+	inviteHandler := &handlers.InviteHandler{Service: inviteSvc}
+	budgetHandler := &handlers.BudgetHandler{Service: budgetSvc}
+	notificationHandler := &handlers.NotificationHandler{Service: notificationSvc}
+	eventHandler := &handlers.EventHandler{Events: eventBus, Tickets: streamTicketSvc}
+	webhookHandler := &handlers.WebhookHandler{Service: webhookSvc}
+	accessTokenHandler := &handlers.AccessTokenHandler{Service: accessTokenSvc}
+	exportHandler := &handlers.ExportHandler{Service: exportSvc}
 
 	// Router
 	r := mux.NewRouter()
@@ -60,21 +160,48 @@ func SetupRouter() http.Handler {
 	// Public Routes (No Token Needed)
 	r.HandleFunc("/api/users/register", userHandler.Register).Methods("POST")
 	r.HandleFunc("/api/users/login", userHandler.Login).Methods("POST")
+	r.HandleFunc("/api/users/login/2fa", userHandler.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/api/users/refresh", userHandler.Refresh).Methods("POST")
 	r.HandleFunc("/api/users/forgot-password", userHandler.ForgotPassword).Methods("POST")
 	r.HandleFunc("/api/users/reset-password", userHandler.ResetPassword).Methods("POST")
+	r.HandleFunc("/api/users/verify-email", userHandler.VerifyEmail).Methods("POST")
+	r.HandleFunc("/api/exports/download", exportHandler.Download).Methods("GET")
+	r.HandleFunc("/.well-known/jwks.json", handlers.JWKS).Methods("GET")
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status": "healthy", "message": "Server is running!"}`))
 	}).Methods("GET")
 
+	// Event stream; EventSource can't send headers, so it authenticates with
+	// a single-use ticket from POST /api/events/ticket instead
+	r.Handle("/api/events", middleware.StreamTicketMiddleware(
+		http.HandlerFunc(eventHandler.Stream),
+	)).Methods("GET")
+
 	// Protected Routes (Token Required)
 	protected := r.PathPrefix("/api").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
 
+	protected.HandleFunc("/events/ticket", eventHandler.CreateTicket).Methods("POST")
 	protected.HandleFunc("/users", userHandler.GetAll).Methods("GET")
 	protected.HandleFunc("/users/profile", userHandler.GetProfile).Methods("GET")
 	protected.HandleFunc("/users/profile", userHandler.UpdateProfile).Methods("PUT")
+	protected.HandleFunc("/users/password", userHandler.ChangePassword).Methods("PUT")
+	protected.HandleFunc("/users/email", userHandler.ChangeEmail).Methods("PUT")
+	protected.HandleFunc("/users/export", exportHandler.RequestExport).Methods("POST")
+	protected.HandleFunc("/users/export/{id}", exportHandler.GetExport).Methods("GET")
+	protected.HandleFunc("/users/account", userHandler.DeleteAccount).Methods("DELETE")
+	protected.HandleFunc("/users/logout", userHandler.Logout).Methods("POST")
+	protected.HandleFunc("/users/logout-all", userHandler.LogoutAll).Methods("POST")
+	protected.HandleFunc("/users/2fa/setup", userHandler.SetupTwoFactor).Methods("POST")
+	protected.HandleFunc("/users/2fa/confirm", userHandler.ConfirmTwoFactor).Methods("POST")
+	protected.HandleFunc("/users/2fa/disable", userHandler.DisableTwoFactor).Methods("POST")
+	protected.HandleFunc("/users/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes).Methods("POST")
+	protected.HandleFunc("/users/verify-email/resend", userHandler.ResendVerification).Methods("POST")
+	protected.HandleFunc("/users/tokens", accessTokenHandler.CreateToken).Methods("POST")
+	protected.HandleFunc("/users/tokens", accessTokenHandler.GetTokens).Methods("GET")
+	protected.HandleFunc("/users/tokens/{id}", accessTokenHandler.RevokeToken).Methods("DELETE")
 	protected.HandleFunc("/users/settlements", settlementHandler.GetUserSettlements).Methods("GET")
 	protected.HandleFunc("/users/balances", balanceHandler.GetUserBalance).Methods("GET")
 
@@ -84,20 +211,44 @@ func SetupRouter() http.Handler {
 	protected.HandleFunc("/groups/{id}", groupHandler.GetGroup).Methods("GET")
 	protected.HandleFunc("/groups/{id}", groupHandler.UpdateGroup).Methods("PUT")
 	protected.HandleFunc("/groups/{id}", groupHandler.DeleteGroup).Methods("DELETE")
+	protected.HandleFunc("/groups/{id}/archive", groupHandler.ArchiveGroup).Methods("PUT")
+	protected.HandleFunc("/groups/{id}/unarchive", groupHandler.UnarchiveGroup).Methods("PUT")
+	protected.HandleFunc("/groups/{id}/default-split", groupHandler.SetDefaultSplit).Methods("PUT")
 	protected.HandleFunc("/groups/{id}/members", groupHandler.AddMember).Methods("POST")
 	protected.HandleFunc("/groups/{id}/members/{uid}", groupHandler.RemoveMember).Methods("DELETE")
+	protected.HandleFunc("/groups/{id}/members/{uid}/role", groupHandler.SetMemberRole).Methods("PUT")
+	protected.HandleFunc("/groups/{id}/ownership", groupHandler.TransferOwnership).Methods("POST")
+	protected.HandleFunc("/groups/{id}/ownership", groupHandler.CancelOwnershipTransfer).Methods("DELETE")
+	protected.HandleFunc("/groups/{id}/ownership/accept", groupHandler.AcceptOwnership).Methods("PUT")
+	protected.HandleFunc("/groups/{id}/ownership/reclaim", groupHandler.ReclaimOwnership).Methods("POST")
+
+	// Invite Routes
+	protected.HandleFunc("/groups/{id}/invites", inviteHandler.CreateInvite).Methods("POST")
+	protected.HandleFunc("/groups/{id}/invites", inviteHandler.GetInvites).Methods("GET")
+	protected.HandleFunc("/groups/{id}/invites/{inviteId}", inviteHandler.RevokeInvite).Methods("DELETE")
+	protected.HandleFunc("/invites/{token}/join", inviteHandler.Join).Methods("POST")
 
 	// Expense Routes
 	protected.HandleFunc("/groups/{id}/expenses", expenseHandler.AddExpense).Methods("POST")
 	protected.HandleFunc("/groups/{id}/expenses", expenseHandler.GetExpenses).Methods("GET")
+	protected.HandleFunc("/expenses/{id}", expenseHandler.UpdateExpense).Methods("PUT")
 	protected.HandleFunc("/expenses/{id}", expenseHandler.DeleteExpense).Methods("DELETE")
 
+	// Budget Routes
+	protected.HandleFunc("/groups/{id}/budgets", budgetHandler.CreateBudget).Methods("POST")
+	protected.HandleFunc("/groups/{id}/budgets", budgetHandler.GetBudgets).Methods("GET")
+	protected.HandleFunc("/groups/{id}/budgets/{budgetId}", budgetHandler.DeleteBudget).Methods("DELETE")
+
 	// Balance Routes
 	protected.HandleFunc("/groups/{id}/balances", balanceHandler.GetBalances).Methods("GET")
 
 	// Settlement Routes
 	protected.HandleFunc("/groups/{id}/settle", settlementHandler.Settle).Methods("POST")
+	protected.HandleFunc("/groups/{id}/settle-all", settlementHandler.SettleAll).Methods("POST")
 	protected.HandleFunc("/groups/{id}/settlements", settlementHandler.GetGroupSettlements).Methods("GET")
+	protected.HandleFunc("/groups/{id}/settlements/pending", settlementHandler.GetPendingSettlements).Methods("GET")
+	protected.HandleFunc("/settlements/{id}/confirm", settlementHandler.ConfirmSettlement).Methods("PUT")
+	protected.HandleFunc("/settlements/{id}/reject", settlementHandler.RejectSettlement).Methods("PUT")
 	protected.HandleFunc("/settlements/{id}", settlementHandler.DeleteSettlement).Methods("DELETE")
 
 	// Friend Routes
@@ -109,6 +260,21 @@ func SetupRouter() http.Handler {
 	protected.HandleFunc("/friends/{id}/reject", friendHandler.RejectRequest).Methods("PUT")
 	protected.HandleFunc("/friends/{id}", friendHandler.RemoveFriend).Methods("DELETE")
 
+	// Notification Routes
+	protected.HandleFunc("/notifications", notificationHandler.GetNotifications).Methods("GET")
+	protected.HandleFunc("/notifications/unread-count", notificationHandler.UnreadCount).Methods("GET")
+	protected.HandleFunc("/notifications/read-all", notificationHandler.MarkAllRead).Methods("PUT")
+	protected.HandleFunc("/notifications/preferences", notificationHandler.GetPreferences).Methods("GET")
+	protected.HandleFunc("/notifications/preferences", notificationHandler.UpdatePreferences).Methods("PUT")
+	protected.HandleFunc("/notifications/{id}/read", notificationHandler.MarkRead).Methods("PUT")
+
+	// Webhook Routes
+	protected.HandleFunc("/webhooks", webhookHandler.CreateWebhook).Methods("POST")
+	protected.HandleFunc("/webhooks", webhookHandler.GetWebhooks).Methods("GET")
+	protected.HandleFunc("/webhooks/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	protected.HandleFunc("/webhooks/{id}/deliveries", webhookHandler.GetDeliveries).Methods("GET")
+	protected.HandleFunc("/webhooks/{id}/ping", webhookHandler.Ping).Methods("POST")
+
 	// Apply middleware: CORS first, then Logger
 	return middleware.CORSMiddleware(middleware.LoggerMiddleware(r))
 }
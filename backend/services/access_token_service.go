@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxAccessTokens        = 20
+	maxAccessTokenLifetime = 365
+)
+
+type AccessTokenService struct {
+	Repo *repository.AccessTokenRepo
+}
+
+func (s *AccessTokenService) CreateToken(userID string, req models.CreateAccessTokenRequest) (*models.CreatedAccessToken, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !containsString(models.AccessTokenScopes, scope) {
+			return nil, errors.New("unknown scope: " + scope)
+		}
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAccessTokenLifetime {
+		return nil, errors.New("expires_in_days must be between 0 and 365")
+	}
+
+	count, err := s.Repo.CountByUser(objID)
+	if err != nil {
+		return nil, errors.New("failed to create access token")
+	}
+	if count >= maxAccessTokens {
+		return nil, errors.New("you can have at most 20 access tokens")
+	}
+
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := models.AccessTokenPrefix + secret
+	token := models.AccessToken{
+		UserID:    objID,
+		Name:      name,
+		Prefix:    raw[:len(models.AccessTokenPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expires
+	}
+	if err := s.Repo.Create(&token); err != nil {
+		return nil, errors.New("failed to create access token")
+	}
+	return &models.CreatedAccessToken{AccessToken: token, Token: raw}, nil
+}
+
+func (s *AccessTokenService) GetTokens(userID string) ([]models.AccessToken, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	return s.Repo.GetByUser(objID)
+}
+
+func (s *AccessTokenService) RevokeToken(userID, tokenID string) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	id, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return errors.New("invalid token id")
+	}
+	deleted, err := s.Repo.Delete(id, objID)
+	if err != nil {
+		return errors.New("failed to revoke access token")
+	}
+	if !deleted {
+		return errors.New("access token not found")
+	}
+	return nil
+}
+
+// Authenticate is the AuthMiddleware hook for personal access tokens. It
+// returns the owner and the token's scopes.
+func (s *AccessTokenService) Authenticate(raw, ip string) (string, []string, error) {
+	token, err := s.Repo.GetByHash(utils.HashToken(raw))
+	if err != nil {
+		return "", nil, errors.New("invalid access token")
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return "", nil, errors.New("access token has expired")
+	}
+	s.Repo.TouchLastUsed(token.ID, ip)
+	return token.UserID.Hex(), token.Scopes, nil
+}
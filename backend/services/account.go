@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"splitwise/mailer"
+	"splitwise/models"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChangePassword replaces the password of a signed-in user. Every other
+// session is signed out and the account's address is told about it.
+func (s *UserService) ChangePassword(userID, sessionID string, req models.ChangePasswordRequest) error {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect")
+	}
+	if len(req.NewPassword) < 6 {
+		return errors.New("password must be at least 6 characters")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("new password must be different from the current one")
+	}
+
+	hashed, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.Repo.UpdatePassword(user.ID, hashed); err != nil {
+		return errors.New("failed to update password")
+	}
+
+	s.revokeOtherSessions(user.ID, sessionID)
+	s.sendSecurityNotice(user, user.Email, "The password for your Splitwise account was just changed.")
+	return nil
+}
+
+// ChangeEmail starts moving the account to a new address. Nothing changes
+// until the link sent to the new address is opened; see VerifyEmail.
+func (s *UserService) ChangeEmail(userID, sessionID string, req models.ChangeEmailRequest) error {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect")
+	}
+
+	email := strings.TrimSpace(req.NewEmail)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return errors.New("invalid email address")
+	}
+	if strings.EqualFold(email, user.Email) {
+		return errors.New("that is already your email address")
+	}
+	if existing, err := s.Repo.GetByEmail(email); err == nil && !existing.Placeholder && existing.EmailVerified {
+		return errors.New("email already in use")
+	}
+
+	// Shares the verification email limits
+	if latest, err := s.Repo.GetLatestEmailVerification(user.ID); err == nil && time.Since(latest.CreatedAt) < verificationResendDelay {
+		return errors.New("please wait a minute before requesting another verification email")
+	}
+	sent, err := s.Repo.CountEmailVerificationsSince(user.ID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= verificationDailyLimit {
+		return errors.New("too many verification emails requested; try again tomorrow")
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+	verification := &models.EmailVerification{
+		UserID:      user.ID,
+		Email:       email,
+		Token:       token,
+		EmailChange: true,
+		ExpiresAt:   time.Now().Add(verificationExpiry),
+	}
+	if sid, err := primitive.ObjectIDFromHex(sessionID); err == nil {
+		verification.SessionID = &sid
+	}
+	if err := s.Repo.CreateEmailVerification(verification); err != nil {
+		return err
+	}
+
+	go func() {
+		msg, err := mailer.Render("email_change", email, "Confirm your new Splitwise email", map[string]string{
+			"Name":      user.Name,
+			"Link":      mailer.BaseURL() + "/verify-email?token=" + url.QueryEscape(token),
+			"ExpiresIn": "24 hours",
+		})
+		if err == nil {
+			err = s.Mailer.Send(msg)
+		}
+		if err != nil {
+			log.Println("Failed to send email change confirmation to", user.ID.Hex(), ":", err)
+		}
+	}()
+	s.sendSecurityNotice(user, user.Email, "Someone asked to change the email address of your Splitwise account to "+email+". It won't change unless the new address is confirmed.")
+	return nil
+}
+
+// completeEmailChange switches the account to a confirmed new address. As
+// on sign-up, a placeholder with that address is merged in and accounts
+// that registered it without verifying are released.
+func (s *UserService) completeEmailChange(user *models.User, verification *models.EmailVerification) error {
+	if existing, err := s.Repo.GetByEmail(verification.Email); err == nil && !existing.Placeholder && existing.EmailVerified {
+		return errors.New("email already in use")
+	}
+	if placeholder, err := s.Repo.GetPlaceholderByEmail(verification.Email); err == nil {
+		if err := s.mergePlaceholder(placeholder.ID, user.ID); err != nil {
+			return errors.New("failed to change email")
+		}
+	}
+
+	if err := s.Repo.UpdateEmail(user.ID, verification.Email); err != nil {
+		return errors.New("failed to change email")
+	}
+	s.Repo.DeleteEmailVerifications(user.ID)
+	s.releaseEmail(verification.Email, user.ID)
+
+	if err := s.Sessions.Repo.RevokeAllForUser(user.ID, verification.SessionID); err != nil {
+		log.Println("Failed to revoke sessions after email change:", err)
+	}
+	s.sendSecurityNotice(user, user.Email, "The email address of your Splitwise account was changed to "+verification.Email+". You will no longer get emails here.")
+	return nil
+}
+
+func (s *UserService) revokeOtherSessions(userID primitive.ObjectID, sessionID string) {
+	var except *primitive.ObjectID
+	if sid, err := primitive.ObjectIDFromHex(sessionID); err == nil {
+		except = &sid
+	}
+	if err := s.Sessions.Repo.RevokeAllForUser(userID, except); err != nil {
+		log.Println("Failed to revoke sessions:", err)
+	}
+}
+
+// sendSecurityNotice emails address about a change to the account, in the
+// background.
+func (s *UserService) sendSecurityNotice(user *models.User, address, message string) {
+	go func() {
+		msg, err := mailer.Render("security_notice", address, "Security alert for your Splitwise account", map[string]string{
+			"Name":    user.Name,
+			"Message": message,
+			"Link":    mailer.BaseURL() + "/forgot-password",
+		})
+		if err == nil {
+			err = s.Mailer.Send(msg)
+		}
+		if err != nil {
+			log.Println("Failed to send security notice to", user.ID.Hex(), ":", err)
+		}
+	}()
+}
+
+// accountDeletionRetryDelay is how long an unfinished deletion is left
+// before RunPendingDeletions picks it up, so it doesn't race the request
+// that started it.
+const (
+	accountDeletionRetryDelay = 10 * time.Minute
+	accountDeletionInterval   = time.Hour
+)
+
+// DeleteAccount removes the user's credentials, friendships and personal
+// data and leaves an anonymized record behind, so expenses and settlements
+// that mention them still balance. It refuses while the user has
+// outstanding balances unless they acknowledge them. Owned groups pass to
+// a successor; a group the user is alone in is deleted with them.
+//
+// The account is locked out first, then the steps in finishDeletion run;
+// each can be repeated safely, so if one fails RunPendingDeletions finishes
+// the job later.
+func (s *UserService) DeleteAccount(userID string, req models.DeleteAccountRequest) error {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		return errors.New("incorrect password")
+	}
+	if user.TwoFactorEnabled {
+		if err := s.checkSecondFactor(user, req.Code); err != nil {
+			return err
+		}
+	}
+
+	groups, err := s.GroupRepo.GetGroupsByUserID(user.ID, true)
+	if err != nil {
+		return errors.New("failed to load your groups")
+	}
+	if !req.AcknowledgeBalances {
+		var unsettled []string
+		for _, group := range groups {
+			net, err := s.GroupSvc.BalanceSvc.groupNet(group.ID)
+			if err != nil {
+				return err
+			}
+			if math.Abs(net[user.ID]) >= 0.01 {
+				unsettled = append(unsettled, group.Name)
+			}
+		}
+		if len(unsettled) > 0 {
+			return errors.New("you have outstanding balances in " + strings.Join(unsettled, ", ") +
+				"; settle up or set acknowledge_balances to delete anyway")
+		}
+	}
+
+	if err := s.Repo.MarkDeletionStarted(user.ID); err != nil {
+		return errors.New("failed to delete account")
+	}
+	// The account is already locked out, so from here on the deletion
+	// counts as done even if a step fails and has to be retried
+	if err := s.finishDeletion(user); err != nil {
+		log.Println("Account deletion of", user.ID.Hex(), "stopped, will retry:", err)
+	}
+	return nil
+}
+
+// RunPendingDeletions finishes interrupted account deletions until ctx is
+// cancelled.
+func (s *UserService) RunPendingDeletions(ctx context.Context) {
+	ticker := time.NewTicker(accountDeletionInterval)
+	defer ticker.Stop()
+	for {
+		users, err := s.Repo.GetStalledDeletions(time.Now().Add(-accountDeletionRetryDelay))
+		if err != nil {
+			log.Println("Failed to list unfinished account deletions:", err)
+		}
+		for i := range users {
+			if err := s.finishDeletion(&users[i]); err != nil {
+				log.Println("Failed to finish deleting account", users[i].ID.Hex(), ":", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishDeletion hands over or leaves the user's groups, removes their data
+// and anonymizes the record. Every step is safe to repeat.
+func (s *UserService) finishDeletion(user *models.User) error {
+	if err := s.Sessions.Repo.RevokeAllForUser(user.ID, nil); err != nil {
+		return err
+	}
+	if err := s.TokenRepo.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.GroupSvc.ReassignOwnership(user.ID); err != nil {
+		return err
+	}
+	groups, err := s.GroupRepo.GetGroupsByUserID(user.ID, true)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		group, err := s.GroupRepo.GetByID(g.ID)
+		if err != nil {
+			continue
+		}
+		if memberRole(group, user.ID) == models.RoleOwner {
+			// Nobody who can take it over. Without former members the group's
+			// history is only the user's own, so it goes too; otherwise
+			// the anonymized owner stays to keep the ledger readable.
+			if len(group.FormerMembers) == 0 {
+				if err := s.GroupSvc.DeleteGroup(group.ID.Hex(), user.ID.Hex()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.GroupSvc.removeMember(group, user.ID, user.ID, true); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Repo.DeleteTokens(user.ID); err != nil {
+		return err
+	}
+	if err := s.FriendRepo.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.NotifyRepo.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.WebhookRepo.DeleteByOwner(user.ID); err != nil {
+		return err
+	}
+	if err := s.ExportRepo.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	s.Attempts.ClearByEmail(models.AttemptLogin, attemptKey(user.Email))
+
+	return s.Repo.Anonymize(user.ID)
+}
@@ -22,37 +22,33 @@ func (s *BalanceService) GetGroupBalances(groupID string) ([]models.BalanceDetai
 	if err != nil {
 		return nil, errors.New("invalid group id")
 	}
-	expenses, err := s.ExpenseRepo.GetByGroup(gID)
+	net, err := s.groupNet(gID)
 	if err != nil {
 		return nil, err
 	}
-	net := make(map[primitive.ObjectID]float64)
-	for _, expense := range expenses {
-		net[expense.PaidBy] += expense.Amount
-		for _, split := range expense.Splits {
-			net[split.UserID] -= split.Amount
+	plan := minimizeTransactions(net)
+
+	// Flag users who left the group with a balance outstanding
+	group, err := s.GroupRepo.GetByID(gID)
+	if err == nil && len(group.FormerMembers) > 0 {
+		for i := range plan {
+			from, _ := primitive.ObjectIDFromHex(plan[i].FromUserID)
+			to, _ := primitive.ObjectIDFromHex(plan[i].ToUser)
+			plan[i].FromFormerMember = isFormerMember(group, from)
+			plan[i].ToFormerMember = isFormerMember(group, to)
 		}
 	}
-
-	// Factor in settlements: a settlement means PaidBy paid PaidTo
-	settlements, err := s.SettlementRepo.GetByGroup(gID)
-	if err != nil {
-		return nil, err
-	}
-	for _, st := range settlements {
-		net[st.PaidBy] += st.Amount
-		net[st.PaidTo] -= st.Amount
-	}
-
-	return minimizeTransactions(net), nil
+	return plan, nil
 }
-func (s *BalanceService) GetUserOverallBalance(userID string) ([]models.BalanceDetail, error) {
+// GetUserOverallBalance combines balances across the user's groups, leaving
+// out archived groups unless includeArchived is set.
+func (s *BalanceService) GetUserOverallBalance(userID string, includeArchived bool) ([]models.BalanceDetail, error) {
 	uID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	groups, err := s.GroupRepo.GetGroupsByUserID(uID)
+	groups, err := s.GroupRepo.GetGroupsByUserID(uID, includeArchived)
 	if err != nil {
 		return nil, err
 	}
@@ -60,29 +56,59 @@ func (s *BalanceService) GetUserOverallBalance(userID string) ([]models.BalanceD
 	net := make(map[primitive.ObjectID]float64)
 
 	for _, group := range groups {
-		expenses, err := s.ExpenseRepo.GetByGroup(group.ID)
+		groupNet, err := s.groupNet(group.ID)
 		if err != nil {
 			continue
 		}
-		for _, expense := range expenses {
-			net[expense.PaidBy] += expense.Amount
-			for _, split := range expense.Splits {
-				net[split.UserID] -= split.Amount
-			}
+		for userID, amount := range groupNet {
+			net[userID] += amount
 		}
+	}
 
-		// Factor in settlements for this group
-		settlements, err := s.SettlementRepo.GetByGroup(group.ID)
-		if err != nil {
-			continue
-		}
-		for _, st := range settlements {
-			net[st.PaidBy] += st.Amount
-			net[st.PaidTo] -= st.Amount
+	return minimizeTransactions(net), nil
+}
+
+// groupNet returns every user's net position in a group: positive means the
+// user is owed money, negative means the user owes money.
+func (s *BalanceService) groupNet(groupID primitive.ObjectID) (map[primitive.ObjectID]float64, error) {
+	expenses, err := s.ExpenseRepo.GetByGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	net := make(map[primitive.ObjectID]float64)
+	for _, expense := range expenses {
+		net[expense.PaidBy] += expense.Amount
+		for _, split := range expense.Splits {
+			net[split.UserID] -= split.Amount
 		}
 	}
 
-	return minimizeTransactions(net), nil
+	// Factor in settlements: a settlement means PaidBy paid PaidTo.
+	// Pending and rejected settlements don't move balances.
+	settlements, err := s.SettlementRepo.GetConfirmedByGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range settlements {
+		net[st.PaidBy] += st.Amount
+		net[st.PaidTo] -= st.Amount
+	}
+	return net, nil
+}
+
+// OutstandingBetween returns how much payer can pay payee in a group without
+// either of them overshooting their net position.
+func (s *BalanceService) OutstandingBetween(groupID, payer, payee primitive.ObjectID) (float64, error) {
+	net, err := s.groupNet(groupID)
+	if err != nil {
+		return 0, err
+	}
+	owes := -net[payer]
+	owed := net[payee]
+	if owes < 0.01 || owed < 0.01 {
+		return 0, nil
+	}
+	return math.Round(math.Min(owes, owed)*100) / 100, nil
 }
 
 func minimizeTransactions(net map[primitive.ObjectID]float64) []models.BalanceDetail {
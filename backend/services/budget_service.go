@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// budgetThresholds are the percentages of a budget that trigger an alert.
+var budgetThresholds = []int{80, 100}
+
+type BudgetService struct {
+	Repo        *repository.BudgetRepo
+	GroupRepo   *repository.GroupRepo
+	ExpenseRepo *repository.ExpenseRepo
+	Notifier    *NotificationService
+}
+
+// CreateBudget adds a total or per-category budget to the group.
+func (s *BudgetService) CreateBudget(groupID string, userID string, req models.CreateBudgetRequest) (*models.Budget, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, errors.New("start_date must be YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return nil, errors.New("end_date must be YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date cannot be before start_date")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	if !can(memberRole(group, uID), permUpdateGroup) {
+		return nil, errors.New("you do not have permission to manage budgets")
+	}
+
+	budget := &models.Budget{
+		GroupID:   gID,
+		Category:  strings.TrimSpace(req.Category),
+		Amount:    req.Amount,
+		StartDate: start,
+		EndDate:   end,
+		CreatedBy: uID,
+	}
+
+	if err := s.Repo.Create(budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// GetBudgets reports spent and remaining amounts for each of the group's budgets.
+func (s *BudgetService) GetBudgets(groupID string, userID string) ([]models.BudgetStatus, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	if !isMember(group.Members, uID) {
+		return nil, errors.New("you do not have permission to view this group's budgets")
+	}
+
+	budgets, err := s.Repo.GetByGroup(gID)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []models.BudgetStatus
+	for _, budget := range budgets {
+		status, err := s.status(budget)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// DeleteBudget removes one of the group's budgets.
+func (s *BudgetService) DeleteBudget(groupID string, budgetID string, userID string) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	bID, err := primitive.ObjectIDFromHex(budgetID)
+	if err != nil {
+		return errors.New("invalid budget id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	budget, err := s.Repo.GetByID(bID)
+	if err != nil || budget.GroupID != gID {
+		return errors.New("budget not found")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	if !can(memberRole(group, uID), permUpdateGroup) {
+		return errors.New("you do not have permission to manage budgets")
+	}
+
+	return s.Repo.DeleteBudget(bID)
+}
+
+// CheckThresholds re-evaluates the budgets any of the given versions of an
+// expense count towards, alerts members about every threshold that has newly
+// been crossed and clears the ones spending dropped back below. Pass the
+// expense as it was before an update or delete too, so budgets it no longer
+// counts towards are checked.
+func (s *BudgetService) CheckThresholds(expenses ...*models.Expense) {
+	if len(expenses) == 0 {
+		return
+	}
+	groupID := expenses[0].GroupID
+	budgets, err := s.Repo.GetByGroup(groupID)
+	if err != nil {
+		log.Println("Failed to load budgets for group", groupID.Hex(), err)
+		return
+	}
+
+	for _, budget := range budgets {
+		covered := false
+		for _, expense := range expenses {
+			if budgetCovers(budget, expense) {
+				covered = true
+			}
+		}
+		if !covered {
+			continue
+		}
+
+		status, err := s.status(budget)
+		if err != nil {
+			log.Println("Failed to compute budget", budget.ID.Hex(), err)
+			continue
+		}
+
+		for _, threshold := range budgetThresholds {
+			if status.PercentUsed < float64(threshold) {
+				if err := s.Repo.ClearAlert(budget.ID, threshold); err != nil {
+					log.Println("Failed to clear budget alert", budget.ID.Hex(), err)
+				}
+				continue
+			}
+
+			fresh, err := s.Repo.MarkAlertSent(budget.ID, threshold)
+			if err != nil {
+				log.Println("Failed to record budget alert", budget.ID.Hex(), err)
+				continue
+			}
+			if fresh {
+				s.alert(status, threshold)
+			}
+		}
+	}
+}
+
+// alert tells every group member that a budget threshold was crossed.
+func (s *BudgetService) alert(status *models.BudgetStatus, threshold int) {
+	group, err := s.GroupRepo.GetByID(status.Budget.GroupID)
+	if err != nil {
+		log.Println("Failed to load group for budget alert", status.Budget.GroupID.Hex(), err)
+		return
+	}
+
+	name := "total"
+	if status.Budget.Category != "" {
+		name = status.Budget.Category
+	}
+	s.Notifier.Notify(memberIDs(group), models.Notification{
+		Type: models.NotifyBudgetAlert,
+		Message: fmt.Sprintf("%s has used %d%% of its %s budget (%.2f of %.2f)",
+			group.Name, threshold, name, status.Spent, status.Budget.Amount),
+		GroupID: &group.ID,
+		RefID:   &status.Budget.ID,
+	})
+}
+
+func (s *BudgetService) status(budget models.Budget) (*models.BudgetStatus, error) {
+	spent, err := s.ExpenseRepo.SumSpent(budget.GroupID, budget.Category, budget.StartDate, budgetEnd(budget))
+	if err != nil {
+		return nil, err
+	}
+	spent = math.Round(spent*100) / 100
+
+	return &models.BudgetStatus{
+		Budget:      budget,
+		Spent:       spent,
+		Remaining:   math.Round((budget.Amount-spent)*100) / 100,
+		PercentUsed: math.Round(spent/budget.Amount*10000) / 100,
+	}, nil
+}
+
+// budgetEnd is the exclusive upper bound of the budget's date range.
+func budgetEnd(budget models.Budget) time.Time {
+	return budget.EndDate.AddDate(0, 0, 1)
+}
+
+// budgetCovers reports whether the expense counts towards the budget.
+func budgetCovers(budget models.Budget, expense *models.Expense) bool {
+	if budget.Category != "" && budget.Category != expense.Category {
+		return false
+	}
+	return !expense.CreatedAt.Before(budget.StartDate) && expense.CreatedAt.Before(budgetEnd(budget))
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"splitwise/models"
+	"splitwise/pubsub"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types published on the bus.
+const (
+	EventExpenseCreated      = "expense.created"
+	EventExpenseUpdated      = "expense.updated"
+	EventExpenseDeleted      = "expense.deleted"
+	EventSettlementCreated   = "settlement.created"
+	EventSettlementConfirmed = "settlement.confirmed"
+	EventSettlementRejected  = "settlement.rejected"
+	EventSettlementDeleted   = "settlement.deleted"
+	EventMemberAdded         = "group.member_added"
+	EventMemberRemoved       = "group.member_removed"
+	EventMemberUpdated       = "group.member_updated"
+)
+
+// memberChange is the payload of membership events.
+type memberChange struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	Role   string             `json:"role,omitempty"`
+}
+
+// EventBus publishes group activity to live subscribers.
+type EventBus struct {
+	Broker pubsub.Broker
+	hooks  []func(pubsub.Event)
+}
+
+// OnPublish registers fn to run for every event published by this process.
+func (b *EventBus) OnPublish(fn func(pubsub.Event)) {
+	b.hooks = append(b.hooks, fn)
+}
+
+// Publish announces an event to recipients. Like Notify, failures are only
+// logged and a nil bus does nothing.
+func (b *EventBus) Publish(kind string, groupID primitive.ObjectID, recipients []primitive.ObjectID, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Println("Failed to encode", kind, "event:", err)
+		return
+	}
+
+	event := pubsub.Event{
+		Type:      kind,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}
+	if !groupID.IsZero() {
+		event.GroupID = groupID.Hex()
+	}
+	for _, uid := range recipients {
+		event.Recipients = append(event.Recipients, uid.Hex())
+	}
+
+	if err := b.Broker.Publish(event); err != nil {
+		log.Println("Failed to publish", kind, "event:", err)
+	}
+	for _, hook := range b.hooks {
+		hook(event)
+	}
+}
+
+// PublishToGroup announces an event to everyone in the group, plus any
+// extra recipients such as a member who was just removed.
+func (b *EventBus) PublishToGroup(kind string, group *models.Group, data interface{}, extra ...primitive.ObjectID) {
+	b.Publish(kind, group.ID, append(memberIDs(group), extra...), data)
+}
+
+// Subscribe streams the events userID is allowed to see until stop is called.
+func (b *EventBus) Subscribe(userID string) (<-chan pubsub.Event, func()) {
+	events, unsubscribe := b.Broker.Subscribe()
+	out := make(chan pubsub.Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		for event := range events {
+			if !containsString(event.Recipients, userID) {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+}
@@ -2,25 +2,47 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"strings"
 
 	"splitwise/models"
 	"splitwise/repository"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ExpenseService struct {
 	Repo      *repository.ExpenseRepo
 	GroupRepo *repository.GroupRepo
+	BudgetSvc *BudgetService
+	Notifier  *NotificationService
+	Events    *EventBus
 }
 
-func (s *ExpenseService) AddExpense(groupID string, req models.AddExpenseRequest) (*models.Expense, error) {
+// changeLedger runs write and bumps the group's ledger version in one
+// transaction, so a client never sees changed balances under an old version.
+func changeLedger(groups *repository.GroupRepo, groupID primitive.ObjectID, write func(ctx mongo.SessionContext) error) error {
+	return repository.WithTransaction(func(ctx mongo.SessionContext) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		return groups.BumpLedgerVersion(ctx, groupID)
+	})
+}
+
+func (s *ExpenseService) AddExpense(groupID string, userID string, req models.AddExpenseRequest) (*models.Expense, error) {
 	gID, err := primitive.ObjectIDFromHex(groupID)
 	if err != nil {
 		return nil, errors.New("invalid group id")
 	}
 
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
 	paidBy, err := primitive.ObjectIDFromHex(req.PaidBy)
 	if err != nil {
 		return nil, errors.New("invalid user id")
@@ -30,24 +52,150 @@ func (s *ExpenseService) AddExpense(groupID string, req models.AddExpenseRequest
 		return nil, errors.New("group not found")
 	}
 
-	// Validate payer is a group member
-	isMember := false
-	memberSet := make(map[primitive.ObjectID]bool)
-	for _, m := range group.Members {
-		memberSet[m] = true
-		if m == paidBy {
-			isMember = true
-		}
+	// Auth: viewers can't add expenses
+	if !can(memberRole(group, uID), permAddExpense) {
+		return nil, errors.New("you do not have permission to add expenses")
+	}
+
+	if group.Archived {
+		return nil, errors.New("group is archived")
+	}
+
+	splits, err := buildSplits(group, paidBy, req)
+	if err != nil {
+		return nil, err
+	}
+
+	expense := &models.Expense{
+		GroupID:     gID,
+		PaidBy:      paidBy,
+		Amount:      req.Amount,
+		Description: req.Description,
+		Category:    strings.TrimSpace(req.Category),
+		Splits:      splits,
+		CreatedBy:   uID,
+	}
+
+	err = changeLedger(s.GroupRepo, gID, func(ctx mongo.SessionContext) error {
+		return s.Repo.CreateExpense(ctx, expense)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.BudgetSvc.CheckThresholds(expense)
+	s.notifyExpense(models.NotifyExpenseAdded, group, expense, uID,
+		fmt.Sprintf("New expense %q of %.2f in %s", expense.Description, expense.Amount, group.Name))
+	s.Events.PublishToGroup(EventExpenseCreated, group, expense)
+	return expense, nil
+}
+
+// UpdateExpense replaces an expense's payer, amount, description and splits.
+// Members can edit their own expenses; owners and admins can edit any.
+func (s *ExpenseService) UpdateExpense(expenseID string, userID string, req models.AddExpenseRequest) (*models.Expense, error) {
+	objID, err := primitive.ObjectIDFromHex(expenseID)
+	if err != nil {
+		return nil, errors.New("invalid expense id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	paidBy, err := primitive.ObjectIDFromHex(req.PaidBy)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	expense, err := s.Repo.GetByID(objID)
+	if err != nil {
+		return nil, errors.New("expense not found")
+	}
+
+	group, err := s.GroupRepo.GetByID(expense.GroupID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	perm := permEditAnyExpense
+	if ownsExpense(expense, uID) {
+		perm = permEditOwnExpense
+	}
+	if !can(memberRole(group, uID), perm) {
+		return nil, errors.New("you do not have permission to edit this expense")
+	}
+
+	if group.Archived {
+		return nil, errors.New("group is archived")
+	}
+
+	splits, err := buildSplits(group, paidBy, req)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *expense
+	expense.PaidBy = paidBy
+	expense.Amount = req.Amount
+	expense.Description = req.Description
+	expense.Category = strings.TrimSpace(req.Category)
+	expense.Splits = splits
+
+	err = changeLedger(s.GroupRepo, expense.GroupID, func(ctx mongo.SessionContext) error {
+		return s.Repo.UpdateExpense(ctx, expense)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if !isMember {
+	s.BudgetSvc.CheckThresholds(&before, expense)
+	s.notifyExpense(models.NotifyExpenseUpdated, group, expense, uID,
+		fmt.Sprintf("Expense %q in %s was changed to %.2f", expense.Description, group.Name, expense.Amount))
+	s.Events.PublishToGroup(EventExpenseUpdated, group, expense)
+	return expense, nil
+}
+
+// notifyExpense tells the payer and everyone in the splits about an expense.
+func (s *ExpenseService) notifyExpense(kind string, group *models.Group, expense *models.Expense, actor primitive.ObjectID, message string) {
+	recipients := []primitive.ObjectID{expense.PaidBy}
+	for _, split := range expense.Splits {
+		recipients = append(recipients, split.UserID)
+	}
+	s.Notifier.Notify(recipients, models.Notification{
+		Type:    kind,
+		Message: message,
+		ActorID: &actor,
+		GroupID: &group.ID,
+		RefID:   &expense.ID,
+	})
+}
+
+// buildSplits validates the payer and works out each participant's share.
+// Viewers never take part in an expense.
+func buildSplits(group *models.Group, paidBy primitive.ObjectID, req models.AddExpenseRequest) ([]models.ExpenseSplit, error) {
+	// Validate payer is a group member
+	if !isParticipant(group, paidBy) {
 		return nil, errors.New("payer must be a member of the group")
 	}
 
 	var splits []models.ExpenseSplit
 
-	if req.SplitsType == "equal" {
-		share := req.Amount / float64(len(group.Members))
-		for _, memberID := range group.Members {
+	if req.SplitsType == "" && len(req.Splits) == 0 && group.DefaultSplit != nil &&
+		group.DefaultSplit.Type != "equal" {
+		if group.DefaultSplit.NeedsUpdate {
+			return nil, errors.New("the group's default split needs updating for its current members; give explicit splits until it is")
+		}
+		return weightedSplits(req.Amount, group.DefaultSplit.Shares), nil
+	}
+
+	if req.SplitsType == "equal" || (req.SplitsType == "" && len(req.Splits) == 0) {
+		var participants []primitive.ObjectID
+		for _, m := range group.Members {
+			if m.Role != models.RoleViewer {
+				participants = append(participants, m.UserID)
+			}
+		}
+		share := req.Amount / float64(len(participants))
+		for _, memberID := range participants {
 			splits = append(splits, models.ExpenseSplit{
 				UserID: memberID,
 				Amount: share,
@@ -60,7 +208,7 @@ func (s *ExpenseService) AddExpense(groupID string, req models.AddExpenseRequest
 			if err != nil {
 				return nil, errors.New("invalid split user id")
 			}
-			if !memberSet[uid] {
+			if !isParticipant(group, uid) {
 				return nil, errors.New("split user must be a member of the group")
 			}
 			splits = append(splits, models.ExpenseSplit{
@@ -74,20 +222,43 @@ func (s *ExpenseService) AddExpense(groupID string, req models.AddExpenseRequest
 			return nil, errors.New("splits must add up to the expense amount")
 		}
 	}
+	return splits, nil
+}
 
-	expense := &models.Expense{
-		GroupID:     gID,
-		PaidBy:      paidBy,
-		Amount:      req.Amount,
-		Description: req.Description,
-		Splits:      splits,
+// weightedSplits divides amount in proportion to each share's value. Shares
+// are rounded to cents and the last one absorbs the rounding difference so
+// the splits always add up to the amount.
+func weightedSplits(amount float64, shares []models.MemberShare) []models.ExpenseSplit {
+	var total float64
+	for _, share := range shares {
+		total += share.Value
 	}
 
-	if err := s.Repo.CreateExpense(expense); err != nil {
-		return nil, err
+	splits := make([]models.ExpenseSplit, 0, len(shares))
+	var assigned float64
+	for i, share := range shares {
+		portion := math.Round(amount*share.Value/total*100) / 100
+		if i == len(shares)-1 {
+			portion = math.Round((amount-assigned)*100) / 100
+		}
+		assigned += portion
+		splits = append(splits, models.ExpenseSplit{
+			UserID: share.UserID,
+			Amount: portion,
+		})
 	}
-	return expense, nil
+	return splits
+}
+
+// ownsExpense reports whether the user created the expense. Expenses from
+// before CreatedBy was tracked belong to whoever paid.
+func ownsExpense(expense *models.Expense, userID primitive.ObjectID) bool {
+	if expense.CreatedBy.IsZero() {
+		return expense.PaidBy == userID
+	}
+	return expense.CreatedBy == userID
 }
+
 func (s *ExpenseService) GetExpenses(groupID string) ([]models.Expense, error) {
 	gID, err := primitive.ObjectIDFromHex(groupID)
 	if err != nil {
@@ -95,10 +266,40 @@ func (s *ExpenseService) GetExpenses(groupID string) ([]models.Expense, error) {
 	}
 	return s.Repo.GetByGroup(gID)
 }
-func (s *ExpenseService) DeleteExpense(expenseID string) error {
+func (s *ExpenseService) DeleteExpense(expenseID string, userID string) error {
 	objID, err := primitive.ObjectIDFromHex(expenseID)
 	if err != nil {
 		return errors.New("invalid expense id")
 	}
-	return s.Repo.DeleteExpense(objID)
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	expense, err := s.Repo.GetByID(objID)
+	if err != nil {
+		return errors.New("expense not found")
+	}
+	group, err := s.GroupRepo.GetByID(expense.GroupID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+	perm := permDeleteAnyExpense
+	if ownsExpense(expense, uID) {
+		perm = permDeleteOwnExpense
+	}
+	if !can(memberRole(group, uID), perm) {
+		return errors.New("you do not have permission to delete this expense")
+	}
+	if group.Archived {
+		return errors.New("group is archived")
+	}
+	err = changeLedger(s.GroupRepo, expense.GroupID, func(ctx mongo.SessionContext) error {
+		return s.Repo.DeleteExpense(ctx, objID)
+	})
+	if err != nil {
+		return err
+	}
+	s.BudgetSvc.CheckThresholds(expense)
+	s.Events.PublishToGroup(EventExpenseDeleted, group, expense)
+	return nil
 }
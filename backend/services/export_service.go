@@ -0,0 +1,375 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// exportLinkExpiry is how long a finished archive can be downloaded.
+	exportLinkExpiry = 24 * time.Hour
+	// exportCooldown is how often a user can start a new export; asking
+	// again sooner returns the latest job.
+	exportCooldown = time.Hour
+	// exportTimeout marks a job that never finished (e.g. the server
+	// restarted mid-build) as abandoned.
+	exportTimeout         = 30 * time.Minute
+	exportCleanupInterval = time.Hour
+	exportTimeFormat      = time.RFC3339
+)
+
+type ExportService struct {
+	Repo           *repository.ExportRepo
+	UserRepo       *repository.UserRepo
+	GroupRepo      *repository.GroupRepo
+	ExpenseRepo    *repository.ExpenseRepo
+	SettlementRepo *repository.SettlementRepo
+	FriendRepo     *repository.FriendRepo
+	NotifyRepo     *repository.NotificationRepo
+	SessionRepo    *repository.SessionRepo
+	TokenRepo      *repository.AccessTokenRepo
+}
+
+// RequestExport starts building an archive of the user's data. Within the
+// cooldown it returns the latest job instead of starting another.
+func (s *ExportService) RequestExport(userID string) (*models.ExportJob, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	if latest, err := s.Repo.GetLatestByUser(uID); err == nil {
+		age := time.Since(latest.CreatedAt)
+		if latest.Status == models.ExportPending && age < exportTimeout {
+			return latest, nil
+		}
+		if latest.Status == models.ExportReady && age < exportCooldown {
+			return s.withDownloadURL(latest)
+		}
+	}
+
+	job := &models.ExportJob{UserID: uID, Status: models.ExportPending}
+	if err := s.Repo.Create(job); err != nil {
+		return nil, errors.New("failed to start export")
+	}
+	go s.build(job)
+	return job, nil
+}
+
+// GetExport returns one of the user's export jobs, with a download link
+// once it is ready.
+func (s *ExportService) GetExport(userID, exportID string) (*models.ExportJob, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	id, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return nil, errors.New("invalid export id")
+	}
+	job, err := s.Repo.GetByID(id)
+	if err != nil || job.UserID != uID {
+		return nil, errors.New("export not found")
+	}
+	if job.Status == models.ExportPending && time.Since(job.CreatedAt) > exportTimeout {
+		job.Status = models.ExportFailed
+		job.Error = "export did not finish, please request a new one"
+	}
+	return s.withDownloadURL(job)
+}
+
+// OpenDownload returns the archive a download link points to. Links are
+// tied to the job rather than a session so they work from a plain browser
+// download.
+func (s *ExportService) OpenDownload(token string) (*models.ExportJob, io.ReadCloser, error) {
+	job, err := s.Repo.GetByTokenHash(utils.HashToken(token))
+	if err != nil || job.Status != models.ExportReady || job.FileID == nil {
+		return nil, nil, errors.New("export not found")
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		return nil, nil, errors.New("download link has expired")
+	}
+	archive, err := s.Repo.OpenArchive(*job.FileID)
+	if err != nil {
+		return nil, nil, errors.New("export not found")
+	}
+	return job, archive, nil
+}
+
+// RunCleanup deletes expired archives until ctx is cancelled.
+func (s *ExportService) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(exportCleanupInterval)
+	defer ticker.Stop()
+	for {
+		jobs, err := s.Repo.GetExpired(time.Now())
+		if err != nil {
+			log.Println("Failed to list expired exports:", err)
+		}
+		for i := range jobs {
+			if err := s.Repo.Delete(&jobs[i]); err != nil {
+				log.Println("Failed to delete export", jobs[i].ID.Hex(), ":", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// withDownloadURL issues a fresh download link for a ready job that hasn't
+// expired. Only the token's hash is stored, so an earlier link can't be shown
+// again; the new one replaces it.
+func (s *ExportService) withDownloadURL(job *models.ExportJob) (*models.ExportJob, error) {
+	if job.Status != models.ExportReady || (job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt)) {
+		return job, nil
+	}
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return nil, errors.New("failed to create download link")
+	}
+	if err := s.Repo.SetTokenHash(job.ID, utils.HashToken(token)); err != nil {
+		return nil, errors.New("failed to create download link")
+	}
+	job.DownloadURL = "/api/exports/download?token=" + url.QueryEscape(token)
+	return job, nil
+}
+
+func (s *ExportService) build(job *models.ExportJob) {
+	data, err := s.archive(job.UserID)
+	if err != nil {
+		log.Println("Export", job.ID.Hex(), "failed:", err)
+		s.Repo.MarkFailed(job.ID, "failed to build export")
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(exportLinkExpiry)
+	job.CompletedAt = &now
+	job.ExpiresAt = &expires
+	if err := s.Repo.SaveArchive(job, data); err != nil {
+		log.Println("Export", job.ID.Hex(), "could not be stored:", err)
+		s.Repo.MarkFailed(job.ID, "failed to store export")
+	}
+}
+
+// archive gathers everything about the user into a ZIP: each dataset as
+// JSON, and the tabular ones as CSV too.
+func (s *ExportService) archive(userID primitive.ObjectID) ([]byte, error) {
+	user, err := s.UserRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.GroupRepo.GetGroupsByUserID(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.ExpenseRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	settlements, err := s.SettlementRepo.GetByUser(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	friends, err := s.FriendRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := s.NotifyRepo.GetByUser(userID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.SessionRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.TokenRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := &exportNames{users: s.UserRepo, groups: s.GroupRepo, userNames: map[primitive.ObjectID]string{}, groupNames: map[primitive.ObjectID]string{}}
+	for _, g := range groups {
+		names.groupNames[g.ID] = g.Name
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", user},
+		{"groups.json", groups},
+		{"expenses.json", expenses},
+		{"settlements.json", settlements},
+		{"friendships.json", friends},
+		{"notifications.json", notifications},
+		{"sessions.json", sessions},
+		{"access_tokens.json", tokens},
+	}
+	for _, f := range files {
+		if err := writeJSON(zw, f.name, f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	groupRows := [][]string{{"id", "name", "role", "joined_at", "archived"}}
+	for _, g := range groups {
+		var joined string
+		for _, m := range g.Members {
+			if m.UserID == userID {
+				joined = m.JoinedAt.Format(exportTimeFormat)
+			}
+		}
+		groupRows = append(groupRows, []string{g.ID.Hex(), g.Name, memberRole(&g, userID), joined, fmt.Sprint(g.Archived)})
+	}
+
+	expenseRows := [][]string{{"id", "date", "group", "description", "category", "amount", "paid_by", "your_share"}}
+	for _, e := range expenses {
+		var share float64
+		for _, split := range e.Splits {
+			if split.UserID == userID {
+				share += split.Amount
+			}
+		}
+		expenseRows = append(expenseRows, []string{
+			e.ID.Hex(), e.CreatedAt.Format(exportTimeFormat), names.group(e.GroupID), e.Description, e.Category,
+			money(e.Amount), names.user(e.PaidBy), money(share),
+		})
+	}
+
+	settlementRows := [][]string{{"id", "paid_at", "group", "paid_by", "paid_to", "amount", "method", "status"}}
+	for _, st := range settlements {
+		settlementRows = append(settlementRows, []string{
+			st.ID.Hex(), st.PaidAt.Format(exportTimeFormat), names.group(st.GroupID),
+			names.user(st.PaidBy), names.user(st.PaidTo), money(st.Amount), st.Method, st.Status,
+		})
+	}
+
+	friendRows := [][]string{{"id", "friend", "direction", "status", "created_at"}}
+	for _, f := range friends {
+		other, direction := f.Addressee, "sent"
+		if f.Addressee == userID {
+			other, direction = f.Requester, "received"
+		}
+		friendRows = append(friendRows, []string{f.ID.Hex(), names.user(other), direction, f.Status, f.CreatedAt.Format(exportTimeFormat)})
+	}
+
+	activityRows := [][]string{{"created_at", "type", "message", "read"}}
+	for _, n := range notifications {
+		activityRows = append(activityRows, []string{n.CreatedAt.Format(exportTimeFormat), n.Type, n.Message, fmt.Sprint(n.Read)})
+	}
+
+	tables := []struct {
+		name string
+		rows [][]string
+	}{
+		{"groups.csv", groupRows},
+		{"expenses.csv", expenseRows},
+		{"settlements.csv", settlementRows},
+		{"friendships.csv", friendRows},
+		{"notifications.csv", activityRows},
+	}
+	for _, t := range tables {
+		if err := writeCSV(zw, t.name, t.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportNames resolves IDs to display names for the CSV files, looking
+// each one up once.
+type exportNames struct {
+	users      *repository.UserRepo
+	groups     *repository.GroupRepo
+	userNames  map[primitive.ObjectID]string
+	groupNames map[primitive.ObjectID]string
+}
+
+func (n *exportNames) user(id primitive.ObjectID) string {
+	if name, ok := n.userNames[id]; ok {
+		return name
+	}
+	name := id.Hex()
+	if user, err := n.users.GetByID(id); err == nil {
+		name = user.Name
+	}
+	n.userNames[id] = name
+	return name
+}
+
+func (n *exportNames) group(id primitive.ObjectID) string {
+	if name, ok := n.groupNames[id]; ok {
+		return name
+	}
+	name := id.Hex()
+	if group, err := n.groups.GetByID(id); err == nil {
+		name = group.Name
+	}
+	n.groupNames[id] = name
+	return name
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func writeJSON(zw *zip.Writer, name string, value interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+func writeCSV(zw *zip.Writer, name string, rows [][]string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = csvSafe(cell)
+		}
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// csvSafe stops spreadsheet apps from reading user-entered text, such as an
+// expense description, as a formula.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
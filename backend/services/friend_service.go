@@ -13,6 +13,7 @@ import (
 type FriendService struct {
 	Repo     *repository.FriendRepo
 	UserRepo *repository.UserRepo
+	Notifier *NotificationService
 }
 
 // SendRequest sends a friend request from the current user to another user.
@@ -31,11 +32,25 @@ func (s *FriendService) SendRequest(userID string, req models.FriendRequest) (*m
 		return nil, errors.New("you cannot send a friend request to yourself")
 	}
 
+	requester, err := s.UserRepo.GetByID(requesterID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !requester.EmailVerified {
+		return nil, errors.New("verify your email before sending friend requests")
+	}
+
 	// Check if the target user exists
-	_, err = s.UserRepo.GetByID(addresseeID)
+	addressee, err := s.UserRepo.GetByID(addresseeID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if addressee.Placeholder {
+		return nil, errors.New("this user hasn't signed up yet")
+	}
+	if !addressee.EmailVerified {
+		return nil, errors.New("this user hasn't verified their email yet")
+	}
 
 	// Check if a friendship already exists between the two users
 	existing, err := s.Repo.FindBetween(requesterID, addresseeID)
@@ -53,6 +68,7 @@ func (s *FriendService) SendRequest(userID string, req models.FriendRequest) (*m
 			existing.Requester = requesterID
 			existing.Addressee = addresseeID
 			existing.Status = "pending"
+			s.notifyFriend(models.NotifyFriendRequest, existing, addresseeID, requesterID, "You have a new friend request")
 			return existing, nil
 		}
 	}
@@ -66,6 +82,7 @@ func (s *FriendService) SendRequest(userID string, req models.FriendRequest) (*m
 	if err := s.Repo.Create(friend); err != nil {
 		return nil, err
 	}
+	s.notifyFriend(models.NotifyFriendRequest, friend, addresseeID, requesterID, "You have a new friend request")
 	return friend, nil
 }
 
@@ -97,7 +114,20 @@ func (s *FriendService) AcceptRequest(userID string, requestID string) error {
 		return errors.New("this request is not pending")
 	}
 
-	return s.Repo.UpdateStatus(rID, "accepted")
+	if err := s.Repo.UpdateStatus(rID, "accepted"); err != nil {
+		return err
+	}
+	s.notifyFriend(models.NotifyFriendRequestAccepts, friend, friend.Requester, uID, "Your friend request was accepted")
+	return nil
+}
+
+func (s *FriendService) notifyFriend(kind string, friend *models.Friend, recipient, actor primitive.ObjectID, message string) {
+	s.Notifier.Notify([]primitive.ObjectID{recipient}, models.Notification{
+		Type:    kind,
+		Message: message,
+		ActorID: &actor,
+		RefID:   &friend.ID,
+	})
 }
 
 // RejectRequest rejects a pending friend request.
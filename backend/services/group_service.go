@@ -2,6 +2,11 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
 
 	"splitwise/models"
 	"splitwise/repository"
@@ -14,16 +19,12 @@ type GroupService struct {
 	UserRepo       *repository.UserRepo
 	ExpenseRepo    *repository.ExpenseRepo
 	SettlementRepo *repository.SettlementRepo
-}
-
-// helper: check if a userID is in the group's members list
-func isMember(members []primitive.ObjectID, userID primitive.ObjectID) bool {
-	for _, m := range members {
-		if m == userID {
-			return true
-		}
-	}
-	return false
+	InviteRepo     *repository.InviteRepo
+	BudgetRepo     *repository.BudgetRepo
+	WebhookRepo    *repository.WebhookRepo
+	BalanceSvc     *BalanceService
+	Notifier       *NotificationService
+	Events         *EventBus
 }
 
 func (s *GroupService) CreateGroup(userID string, req models.CreateGroupRequest) (*models.Group, error) {
@@ -35,7 +36,9 @@ func (s *GroupService) CreateGroup(userID string, req models.CreateGroupRequest)
 	group := &models.Group{
 		Name:      req.Name,
 		CreatedBy: objID,
-		Members:   []primitive.ObjectID{objID},
+		Members: []models.GroupMember{
+			{UserID: objID, Role: models.RoleOwner, JoinedAt: time.Now()},
+		},
 	}
 
 	if err := s.Repo.CreateGroup(group); err != nil {
@@ -84,15 +87,27 @@ func (s *GroupService) UpdateGroup(groupID string, userID string, req models.Upd
 		return errors.New("group not found")
 	}
 
-	// Auth: only creator can rename the group
-	if group.CreatedBy != uID {
-		return errors.New("only the group creator can update the group")
+	// Auth: owners and admins can rename the group
+	if !can(memberRole(group, uID), permUpdateGroup) {
+		return errors.New("you do not have permission to update this group")
 	}
 
-	if req.Name == "" {
+	if req.Name == "" && req.SettlementPolicy == "" {
 		return errors.New("group name cannot be empty")
 	}
 
+	if req.SettlementPolicy != "" {
+		if req.SettlementPolicy != "warn" && req.SettlementPolicy != "reject" {
+			return errors.New("settlement policy must be warn or reject")
+		}
+		if err := s.Repo.UpdateSettlementPolicy(gID, req.SettlementPolicy); err != nil {
+			return err
+		}
+	}
+
+	if req.Name == "" {
+		return nil
+	}
 	return s.Repo.UpdateGroupName(gID, req.Name)
 }
 
@@ -107,36 +122,126 @@ func (s *GroupService) AddMember(groupID string, userID string, req models.AddMe
 		return errors.New("invalid user id")
 	}
 
-	newMemberID, err := primitive.ObjectIDFromHex(req.UserID)
-	if err != nil {
-		return errors.New("invalid member user id")
-	}
-
 	group, err := s.Repo.GetByID(gID)
 	if err != nil {
 		return errors.New("group not found")
 	}
 
 	// Auth: only existing members can add new members
-	if !isMember(group.Members, requestingUser) {
+	role := memberRole(group, requestingUser)
+	if role == "" {
 		return errors.New("you are not a member of this group")
 	}
+	if !can(role, permAddMember) {
+		return errors.New("you do not have permission to add members")
+	}
 
-	// Validate: check if the user being added actually exists
-	_, err = s.UserRepo.GetByID(newMemberID)
+	newRole := req.Role
+	if newRole == "" {
+		newRole = models.RoleMember
+	}
+	if !validRole(newRole) || newRole == models.RoleOwner {
+		return errors.New("role must be admin, member or viewer")
+	}
+	if newRole == models.RoleAdmin && !can(role, permManageAdmins) {
+		return errors.New("you do not have permission to add admins")
+	}
+	if newRole == models.RoleViewer && !can(role, permManageRoles) {
+		return errors.New("you do not have permission to add viewers")
+	}
+
+	newMember, err := s.resolveNewMember(req)
 	if err != nil {
-		return errors.New("user to be added does not exist")
+		return err
+	}
+	if newMember.Placeholder && newRole == models.RoleAdmin {
+		return errors.New("placeholder members cannot be admins")
 	}
+	newMemberID := newMember.ID
 
 	// Check if already a member
 	if isMember(group.Members, newMemberID) {
 		return errors.New("user is already a member of this group")
 	}
 
-	return s.Repo.AddMember(gID, newMemberID)
+	if err := s.Repo.AddMember(gID, models.GroupMember{UserID: newMemberID, Role: newRole}); err != nil {
+		return err
+	}
+	if err := s.revalidateDefaultSplit(gID); err != nil {
+		return err
+	}
+	s.Notifier.Notify([]primitive.ObjectID{newMemberID}, models.Notification{
+		Type:    models.NotifyAddedToGroup,
+		Message: fmt.Sprintf("You were added to %s", group.Name),
+		ActorID: &requestingUser,
+		GroupID: &gID,
+	})
+	s.publishMembership(EventMemberAdded, gID, memberChange{UserID: newMemberID, Role: newRole})
+	return nil
 }
 
-func (s *GroupService) RemoveMember(groupID string, userID string, targetUserID string) error {
+// publishMembership announces a membership change to the group as it is
+// after the change.
+func (s *GroupService) publishMembership(kind string, groupID primitive.ObjectID, change memberChange, extra ...primitive.ObjectID) {
+	if s.Events == nil {
+		return
+	}
+	group, err := s.Repo.GetByID(groupID)
+	if err != nil {
+		return
+	}
+	s.Events.PublishToGroup(kind, group, change, extra...)
+}
+
+// resolveNewMember finds the user being added, either by id or by email. If
+// nobody has signed up with that email yet, a placeholder user is created so
+// they can take part in splits until they register.
+func (s *GroupService) resolveNewMember(req models.AddMemberRequest) (*models.User, error) {
+	if req.UserID != "" {
+		newMemberID, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			return nil, errors.New("invalid member user id")
+		}
+		// Validate: check if the user being added actually exists
+		user, err := s.UserRepo.GetByID(newMemberID)
+		if err != nil || user.Deleted {
+			return nil, errors.New("user to be added does not exist")
+		}
+		if !user.Placeholder && !user.EmailVerified {
+			return nil, errors.New("this user hasn't verified their email yet")
+		}
+		return user, nil
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if req.Name == "" || !strings.Contains(email, "@") {
+		return nil, errors.New("user_id, or name and a valid email, is required")
+	}
+
+	// An unverified account doesn't prove who owns the email, so add a
+	// placeholder instead; it is merged in once the email is verified
+	if existing, err := s.UserRepo.GetByEmail(email); err == nil && (existing.Placeholder || existing.EmailVerified) {
+		return existing, nil
+	}
+	if existing, err := s.UserRepo.GetPlaceholderByEmail(email); err == nil {
+		return existing, nil
+	}
+
+	placeholder := &models.User{
+		Name:        req.Name,
+		Email:       email,
+		Placeholder: true,
+	}
+	if err := s.UserRepo.CreateUser(placeholder); err != nil {
+		return nil, err
+	}
+	return placeholder, nil
+}
+
+// RemoveMember takes a user out of the group. A member whose net balance isn't
+// zero can only be removed with force, by an owner or admin; they are then
+// kept as a former member so the group's balances still show them.
+func (s *GroupService) RemoveMember(groupID string, userID string, targetUserID string, force bool) error {
 	gID, err := primitive.ObjectIDFromHex(groupID)
 	if err != nil {
 		return errors.New("invalid group id")
@@ -157,21 +262,497 @@ func (s *GroupService) RemoveMember(groupID string, userID string, targetUserID
 		return errors.New("group not found")
 	}
 
-	// Prevent removing the creator
-	if targetUser == group.CreatedBy {
-		return errors.New("cannot remove the group creator")
+	targetRole := memberRole(group, targetUser)
+	if targetRole == "" {
+		return errors.New("user is not a member of this group")
+	}
+
+	// Prevent removing the owner; ownership has to move first
+	if targetRole == models.RoleOwner {
+		return errors.New("cannot remove the group owner")
+	}
+
+	// Auth: members can always leave; removing someone else needs permission,
+	// and only the owner can remove an admin
+	if requestingUser != targetUser {
+		role := memberRole(group, requestingUser)
+		if !can(role, permRemoveMember) {
+			return errors.New("you do not have permission to remove members")
+		}
+		if targetRole == models.RoleAdmin && !can(role, permManageAdmins) {
+			return errors.New("you do not have permission to remove admins")
+		}
+	}
+	if force && !can(memberRole(group, requestingUser), permRemoveMember) {
+		return errors.New("you do not have permission to force a removal")
+	}
+
+	return s.removeMember(group, targetUser, requestingUser, force)
+}
+
+// removeMember does the balance check and removal once the caller has been
+// authorised.
+func (s *GroupService) removeMember(group *models.Group, targetUser, removedBy primitive.ObjectID, force bool) error {
+	net, err := s.BalanceSvc.groupNet(group.ID)
+	if err != nil {
+		return err
+	}
+	balance := math.Round(net[targetUser]*100) / 100
+
+	if math.Abs(balance) < 0.01 {
+		err = s.Repo.RemoveMember(group.ID, targetUser)
+	} else if !force {
+		return fmt.Errorf("member has an outstanding balance of %.2f; settle up before leaving the group", balance)
+	} else {
+		err = s.Repo.RemoveMemberAsFormer(group.ID, models.FormerMember{
+			UserID:    targetUser,
+			Role:      memberRole(group, targetUser),
+			Balance:   balance,
+			RemovedBy: removedBy,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if group.PendingOwner != nil && *group.PendingOwner == targetUser {
+		if err := s.Repo.SetPendingOwner(group.ID, nil); err != nil {
+			return err
+		}
+	}
+	if err := s.revalidateDefaultSplit(group.ID); err != nil {
+		return err
+	}
+	s.publishMembership(EventMemberRemoved, group.ID, memberChange{UserID: targetUser}, targetUser)
+	return nil
+}
+
+// SetMemberRole changes a member's role. Only the owner can grant or revoke
+// admin; admins can move people between member and viewer.
+func (s *GroupService) SetMemberRole(groupID string, userID string, targetUserID string, req models.UpdateMemberRoleRequest) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	requestingUser, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	targetUser, err := primitive.ObjectIDFromHex(targetUserID)
+	if err != nil {
+		return errors.New("invalid target user id")
+	}
+
+	if !validRole(req.Role) || req.Role == models.RoleOwner {
+		return errors.New("role must be admin, member or viewer")
 	}
 
-	// Auth: only the creator or the user themselves can remove
-	if requestingUser != group.CreatedBy && requestingUser != targetUser {
-		return errors.New("only the group creator or the member themselves can remove a member")
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
 	}
 
-	if !isMember(group.Members, targetUser) {
+	targetRole := memberRole(group, targetUser)
+	if targetRole == "" {
 		return errors.New("user is not a member of this group")
 	}
+	if targetRole == models.RoleOwner {
+		return errors.New("the owner's role cannot be changed")
+	}
+
+	role := memberRole(group, requestingUser)
+	if !can(role, permManageRoles) {
+		return errors.New("you do not have permission to change roles")
+	}
+	if (targetRole == models.RoleAdmin || req.Role == models.RoleAdmin) && !can(role, permManageAdmins) {
+		return errors.New("you do not have permission to grant or revoke admin")
+	}
 
-	return s.Repo.RemoveMember(gID, targetUser)
+	if err := s.Repo.UpdateMemberRole(gID, targetUser, req.Role); err != nil {
+		return err
+	}
+	// Viewers can't hold shares of the default split
+	if err := s.revalidateDefaultSplit(gID); err != nil {
+		return err
+	}
+	s.publishMembership(EventMemberUpdated, gID, memberChange{UserID: targetUser, Role: req.Role})
+	return nil
+}
+
+// TransferOwnership offers ownership of the group to another member. Nothing
+// changes until they accept.
+func (s *GroupService) TransferOwnership(groupID string, userID string, req models.TransferOwnershipRequest) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	newOwner, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return errors.New("invalid new owner id")
+	}
+
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	if memberRole(group, uID) != models.RoleOwner {
+		return errors.New("only the group owner can transfer ownership")
+	}
+	if newOwner == uID {
+		return errors.New("you already own this group")
+	}
+	if !isParticipant(group, newOwner) {
+		return errors.New("new owner must be a member of the group")
+	}
+
+	return s.Repo.SetPendingOwner(gID, &newOwner)
+}
+
+// AcceptOwnership completes a pending transfer. The previous owner stays in
+// the group as an admin.
+func (s *GroupService) AcceptOwnership(groupID string, userID string) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	if group.PendingOwner == nil || *group.PendingOwner != uID {
+		return errors.New("no ownership transfer is pending for you")
+	}
+
+	owner, _ := groupOwner(group)
+	if err := s.Repo.TransferOwnership(gID, owner, uID); err != nil {
+		return err
+	}
+	s.publishMembership(EventMemberUpdated, gID, memberChange{UserID: uID, Role: models.RoleOwner})
+	return nil
+}
+
+// CancelOwnershipTransfer lets the owner withdraw an offer or the offered
+// member decline it.
+func (s *GroupService) CancelOwnershipTransfer(groupID string, userID string) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	if group.PendingOwner == nil {
+		return errors.New("no ownership transfer is pending")
+	}
+	if *group.PendingOwner != uID && memberRole(group, uID) != models.RoleOwner {
+		return errors.New("only the group owner or the offered member can cancel the transfer")
+	}
+
+	return s.Repo.SetPendingOwner(gID, nil)
+}
+
+// ReclaimOwnership lets an admin take over a group whose owner's account no
+// longer exists.
+func (s *GroupService) ReclaimOwnership(groupID string, userID string) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	if memberRole(group, uID) != models.RoleAdmin {
+		return errors.New("only a group admin can reclaim ownership")
+	}
+
+	owner, ok := groupOwner(group)
+	if ok && s.accountExists(owner) {
+		return errors.New("the group owner's account is still active")
+	}
+
+	if err := s.Repo.TransferOwnership(gID, owner, uID); err != nil {
+		return err
+	}
+	s.publishMembership(EventMemberUpdated, gID, memberChange{UserID: uID, Role: models.RoleOwner})
+	return nil
+}
+
+// ReassignOwnership hands every group the user owns to a successor: the
+// longest-standing admin, otherwise the longest-standing member, otherwise
+// the longest-standing viewer. Placeholders and accounts that are deleted
+// or being deleted are passed over. Groups with nobody else to take them
+// are left as they are.
+func (s *GroupService) ReassignOwnership(userID primitive.ObjectID) error {
+	groups, err := s.Repo.GetGroupsOwnedBy(userID)
+	if err != nil {
+		return err
+	}
+	for i := range groups {
+		successor, ok := ownershipSuccessor(&groups[i], userID, s.canOwn)
+		if !ok {
+			continue
+		}
+		if err := s.Repo.TransferOwnership(groups[i].ID, userID, successor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ownershipSuccessor(group *models.Group, owner primitive.ObjectID, eligible func(primitive.ObjectID) bool) (primitive.ObjectID, bool) {
+	if group.PendingOwner != nil && *group.PendingOwner != owner &&
+		isParticipant(group, *group.PendingOwner) && eligible(*group.PendingOwner) {
+		return *group.PendingOwner, true
+	}
+	for _, role := range []string{models.RoleAdmin, models.RoleMember, models.RoleViewer} {
+		var best *models.GroupMember
+		for i, m := range group.Members {
+			if m.UserID == owner || m.Role != role {
+				continue
+			}
+			if (best == nil || m.JoinedAt.Before(best.JoinedAt)) && eligible(m.UserID) {
+				best = &group.Members[i]
+			}
+		}
+		if best != nil {
+			return best.UserID, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+// groupOwner returns the member holding the owner role.
+func groupOwner(group *models.Group) (primitive.ObjectID, bool) {
+	for _, m := range group.Members {
+		if m.Role == models.RoleOwner {
+			return m.UserID, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+func (s *GroupService) accountExists(userID primitive.ObjectID) bool {
+	user, err := s.UserRepo.GetByID(userID)
+	return err == nil && !user.Deleted
+}
+
+// canOwn reports whether the user can take over a group: a registered
+// account that isn't deleted or on its way to being deleted.
+func (s *GroupService) canOwn(userID primitive.ObjectID) bool {
+	user, err := s.UserRepo.GetByID(userID)
+	return err == nil && !user.Placeholder && !user.Deleted && user.DeletionStartedAt == nil
+}
+
+// ArchiveGroup archives a group, optionally refusing while anyone in it still
+// owes money.
+func (s *GroupService) ArchiveGroup(groupID string, userID string, req models.ArchiveGroupRequest) error {
+	group, err := s.groupForUpdate(groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	if group.Archived {
+		return errors.New("group is already archived")
+	}
+
+	if req.RequireZeroBalance {
+		net, err := s.BalanceSvc.groupNet(group.ID)
+		if err != nil {
+			return err
+		}
+		for _, amount := range net {
+			if math.Abs(amount) >= 0.01 {
+				return errors.New("group still has outstanding balances")
+			}
+		}
+	}
+
+	return s.Repo.SetArchived(group.ID, true)
+}
+
+func (s *GroupService) UnarchiveGroup(groupID string, userID string) error {
+	group, err := s.groupForUpdate(groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !group.Archived {
+		return errors.New("group is not archived")
+	}
+
+	return s.Repo.SetArchived(group.ID, false)
+}
+
+// SetDefaultSplit changes how expenses without explicit splits are divided.
+func (s *GroupService) SetDefaultSplit(groupID string, userID string, req models.UpdateDefaultSplitRequest) (*models.SplitConfig, error) {
+	group, err := s.groupForUpdate(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "equal" {
+		if len(req.Shares) > 0 {
+			return nil, errors.New("equal splits don't take shares")
+		}
+		return nil, s.Repo.SetDefaultSplit(group.ID, nil)
+	}
+
+	config := &models.SplitConfig{Type: req.Type}
+	for _, sh := range req.Shares {
+		uid, err := primitive.ObjectIDFromHex(sh.UserID)
+		if err != nil {
+			return nil, errors.New("invalid share user id")
+		}
+		config.Shares = append(config.Shares, models.MemberShare{UserID: uid, Value: sh.Value})
+	}
+
+	if err := validateSplitConfig(group, config); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.SetDefaultSplit(group.ID, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validateSplitConfig checks a default split against the group's current
+// participants.
+func validateSplitConfig(group *models.Group, config *models.SplitConfig) error {
+	if config.Type != "shares" && config.Type != "percentage" {
+		return errors.New("split type must be equal, shares or percentage")
+	}
+	if len(config.Shares) == 0 {
+		return errors.New("shares are required for a shares or percentage split")
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	var total float64
+	for _, share := range config.Shares {
+		if !isParticipant(group, share.UserID) {
+			return errors.New("share user must be a member of the group")
+		}
+		if seen[share.UserID] {
+			return errors.New("each member can only have one share")
+		}
+		seen[share.UserID] = true
+		if share.Value <= 0 {
+			return errors.New("share values must be greater than 0")
+		}
+		total += share.Value
+	}
+
+	if config.Type == "percentage" && math.Abs(total-100) > 0.01 {
+		return errors.New("percentages must add up to 100")
+	}
+	return nil
+}
+
+// revalidateDefaultSplit runs after membership changes. Shares of people who
+// no longer take part are dropped. If the rest no longer make a valid split
+// for the current participants (someone joined without a share, or
+// percentages no longer add up to 100) the config is kept but marked as
+// needing an update, and the members who can change it are told.
+func (s *GroupService) revalidateDefaultSplit(groupID primitive.ObjectID) error {
+	group, err := s.Repo.GetByID(groupID)
+	if err != nil || group.DefaultSplit == nil {
+		return err
+	}
+
+	config := &models.SplitConfig{Type: group.DefaultSplit.Type}
+	hasShare := make(map[primitive.ObjectID]bool)
+	for _, share := range group.DefaultSplit.Shares {
+		if isParticipant(group, share.UserID) {
+			config.Shares = append(config.Shares, share)
+			hasShare[share.UserID] = true
+		}
+	}
+	for _, m := range group.Members {
+		if isParticipant(group, m.UserID) && !hasShare[m.UserID] {
+			config.NeedsUpdate = true
+		}
+	}
+	if validateSplitConfig(group, config) != nil {
+		config.NeedsUpdate = true
+	}
+
+	if !config.NeedsUpdate && !group.DefaultSplit.NeedsUpdate &&
+		len(config.Shares) == len(group.DefaultSplit.Shares) {
+		return nil
+	}
+	if err := s.Repo.SetDefaultSplit(groupID, config); err != nil {
+		return err
+	}
+	if config.NeedsUpdate && !group.DefaultSplit.NeedsUpdate {
+		log.Println("Default split of group", groupID.Hex(), "needs updating after a membership change")
+		var admins []primitive.ObjectID
+		for _, m := range group.Members {
+			if can(m.Role, permUpdateGroup) {
+				admins = append(admins, m.UserID)
+			}
+		}
+		s.Notifier.Notify(admins, models.Notification{
+			Type:    models.NotifyDefaultSplitOutdated,
+			Message: fmt.Sprintf("The default split of %s no longer covers every member; expenses need explicit splits until it is updated", group.Name),
+			GroupID: &group.ID,
+		})
+	}
+	return nil
+}
+
+// groupForUpdate loads a group and checks the user may change its settings.
+func (s *GroupService) groupForUpdate(groupID string, userID string) (*models.Group, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	group, err := s.Repo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	if !can(memberRole(group, uID), permUpdateGroup) {
+		return nil, errors.New("you do not have permission to update this group")
+	}
+	return group, nil
 }
 
 func (s *GroupService) DeleteGroup(groupID string, userID string) error {
@@ -190,9 +771,9 @@ func (s *GroupService) DeleteGroup(groupID string, userID string) error {
 		return errors.New("group not found")
 	}
 
-	// Auth: only the creator can delete the group
-	if group.CreatedBy != uID {
-		return errors.New("only the group creator can delete the group")
+	// Auth: only the owner can delete the group
+	if !can(memberRole(group, uID), permDeleteGroup) {
+		return errors.New("you do not have permission to delete this group")
 	}
 
 	// Cascade: delete all expenses and settlements for this group
@@ -202,22 +783,31 @@ func (s *GroupService) DeleteGroup(groupID string, userID string) error {
 	if err := s.SettlementRepo.DeleteByGroupID(gID); err != nil {
 		return errors.New("failed to delete group settlements")
 	}
+	if err := s.InviteRepo.DeleteByGroupID(gID); err != nil {
+		return errors.New("failed to delete group invites")
+	}
+	if err := s.BudgetRepo.DeleteByGroupID(gID); err != nil {
+		return errors.New("failed to delete group budgets")
+	}
+	if err := s.WebhookRepo.DeleteByGroupID(gID); err != nil {
+		return errors.New("failed to delete group webhooks")
+	}
 
 	return s.Repo.DeleteGroup(gID)
 }
 
-func (s *GroupService) GetGroupsByUserID(userID string) ([]models.Group, error) {
+func (s *GroupService) GetGroupsByUserID(userID string, includeArchived bool) ([]models.Group, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user id")
 	}
-	return s.Repo.GetGroupsByUserID(objID)
+	return s.Repo.GetGroupsByUserID(objID, includeArchived)
 }
 
-func (s *GroupService) GetUserGroups(userID string) ([]models.Group, error) {
+func (s *GroupService) GetUserGroups(userID string, includeArchived bool) ([]models.Group, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user id")
 	}
-	return s.Repo.GetGroupsByUserID(objID)
+	return s.Repo.GetGroupsByUserID(objID, includeArchived)
 }
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type InviteService struct {
+	Repo      *repository.InviteRepo
+	GroupRepo *repository.GroupRepo
+	UserRepo  *repository.UserRepo
+	GroupSvc  *GroupService
+	Events    *EventBus
+}
+
+// CreateInvite issues a new invite token for the group.
+func (s *InviteService) CreateInvite(groupID string, userID string, req models.CreateInviteRequest) (*models.GroupInvite, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	if req.MaxUses < 0 {
+		return nil, errors.New("max_uses cannot be negative")
+	}
+	if req.ExpiresInHours < 0 {
+		return nil, errors.New("expires_in_hours cannot be negative")
+	}
+	expiresIn := time.Duration(req.ExpiresInHours) * time.Hour
+	if expiresIn == 0 {
+		expiresIn = 7 * 24 * time.Hour
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	if !can(memberRole(group, uID), permCreateInvite) {
+		return nil, errors.New("you do not have permission to create invites")
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return nil, errors.New("failed to generate invite token")
+	}
+
+	invite := &models.GroupInvite{
+		GroupID:   gID,
+		Token:     token,
+		CreatedBy: uID,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+
+	if err := s.Repo.Create(invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// GetActiveInvites lists the group's invites that can still be redeemed.
+func (s *InviteService) GetActiveInvites(groupID string, userID string) ([]models.GroupInvite, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	if !can(memberRole(group, uID), permCreateInvite) {
+		return nil, errors.New("you do not have permission to view invites")
+	}
+
+	return s.Repo.GetActiveByGroup(gID)
+}
+
+// RevokeInvite disables an invite. Members can revoke invites they created;
+// owners and admins can revoke any.
+func (s *InviteService) RevokeInvite(groupID string, inviteID string, userID string) error {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid group id")
+	}
+
+	iID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return errors.New("invalid invite id")
+	}
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	invite, err := s.Repo.GetByID(iID)
+	if err != nil || invite.GroupID != gID {
+		return errors.New("invite not found")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+
+	role := memberRole(group, uID)
+	if !can(role, permRevokeAnyInvite) && !(invite.CreatedBy == uID && can(role, permCreateInvite)) {
+		return errors.New("you do not have permission to revoke this invite")
+	}
+
+	return s.Repo.Revoke(iID)
+}
+
+// JoinByToken adds the caller to the invite's group as a member.
+func (s *InviteService) JoinByToken(token string, userID string) (*models.Group, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	user, err := s.UserRepo.GetByID(uID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.EmailVerified {
+		return nil, errors.New("verify your email before joining groups")
+	}
+
+	invite, err := s.Repo.GetByToken(token)
+	if err != nil {
+		return nil, errors.New("invite is invalid or has expired")
+	}
+
+	group, err := s.GroupRepo.GetByID(invite.GroupID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	// Check membership first so members re-opening a link don't use it up
+	if isMember(group.Members, uID) {
+		return nil, errors.New("you are already a member of this group")
+	}
+
+	if _, err := s.Repo.Redeem(token); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("invite is invalid or has expired")
+		}
+		return nil, err
+	}
+
+	if err := s.GroupRepo.AddMember(group.ID, models.GroupMember{UserID: uID, Role: models.RoleMember}); err != nil {
+		return nil, err
+	}
+	if err := s.GroupSvc.revalidateDefaultSplit(group.ID); err != nil {
+		return nil, err
+	}
+
+	group, err = s.GroupRepo.GetByID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.Events.PublishToGroup(EventMemberAdded, group, memberChange{UserID: uID, Role: models.RoleMember})
+	return group, nil
+}
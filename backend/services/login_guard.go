@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"splitwise/models"
+	"splitwise/utils"
+)
+
+// Login throttling. Failures are counted per account and per IP over
+// attemptWindow. From accountDelayAfter failures on, each retry must wait
+// twice as long as the last (capped at maxLoginDelay); at accountLockAfter
+// the account is locked until lockoutDuration passes or the password is
+// reset.
+const (
+	attemptWindow     = 15 * time.Minute
+	accountDelayAfter = 3
+	maxLoginDelay     = time.Minute
+	accountLockAfter  = 10
+	lockoutDuration   = time.Hour
+	ipFailureLimit    = 30
+
+	// Reset emails: per IP it's a hard limit; per address extra requests
+	// are dropped silently so the response can't reveal anything.
+	forgotPasswordIPLimit    = 10
+	forgotPasswordEmailLimit = 3
+	forgotPasswordWindow     = time.Hour
+)
+
+func attemptKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginAllowed refuses a login before the password is checked, which
+// also keeps throttled requests from costing a bcrypt comparison.
+func (s *UserService) checkLoginAllowed(email, ip string, user *models.User) error {
+	since := time.Now().Add(-attemptWindow)
+
+	ipFailures, err := s.Attempts.CountByIPSince(models.AttemptLogin, ip, since)
+	if err == nil && ipFailures >= ipFailureLimit {
+		return errors.New("too many failed logins from this address, try again later")
+	}
+
+	if user != nil && user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return errors.New("account is temporarily locked, reset your password to unlock it")
+	}
+
+	failures, err := s.Attempts.CountByEmailSince(models.AttemptLogin, email, since)
+	if err != nil || failures < accountDelayAfter {
+		return nil
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-accountDelayAfter))) * time.Second
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	last, err := s.Attempts.LastByEmail(models.AttemptLogin, email)
+	if err != nil {
+		return nil
+	}
+	if wait := time.Until(last.Add(delay)); wait > 0 {
+		return fmt.Errorf("too many failed logins, try again in %d seconds", int(math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login and locks the account once it
+// reaches accountLockAfter.
+func (s *UserService) recordLoginFailure(email, ip string, user *models.User) {
+	if err := s.Attempts.Record(models.AttemptLogin, email, ip); err != nil {
+		log.Println("Failed to record login attempt:", err)
+	}
+	if user == nil {
+		return
+	}
+
+	failures, err := s.Attempts.CountByEmailSince(models.AttemptLogin, email, time.Now().Add(-attemptWindow))
+	if err != nil || failures < accountLockAfter {
+		return
+	}
+	until := time.Now().Add(lockoutDuration)
+	if err := s.Repo.Lock(user.ID, until); err != nil {
+		log.Println("Failed to lock account:", err)
+		return
+	}
+	event := &models.LockoutEvent{UserID: user.ID, Email: email, IP: ip, Failures: failures, LockedUntil: until}
+	if err := s.Attempts.RecordLockout(event); err != nil {
+		log.Println("Failed to record lockout:", err)
+	}
+	log.Printf("Locked account %s after %d failed logins (last from %s)", user.ID.Hex(), failures, ip)
+}
+
+// recordLoginSuccess forgets earlier failures. With 2FA on, a login only
+// succeeds once the second factor is accepted.
+func (s *UserService) recordLoginSuccess(email string) {
+	s.Attempts.ClearByEmail(models.AttemptLogin, email)
+}
+
+// upgradePasswordHash rehashes the password if it was made at an older
+// bcrypt cost.
+func (s *UserService) upgradePasswordHash(password string, user *models.User) {
+	if utils.NeedsRehash(user.Password) {
+		if hashed, err := utils.HashPassword(password); err == nil {
+			s.Repo.UpdatePassword(user.ID, hashed)
+		}
+	}
+}
+
+// allowPasswordReset throttles reset requests. It returns an error only for
+// the per-IP limit; a busy address just gets no further emails.
+func (s *UserService) allowPasswordReset(email, ip string) (bool, error) {
+	since := time.Now().Add(-forgotPasswordWindow)
+
+	byIP, err := s.Attempts.CountByIPSince(models.AttemptForgotPassword, ip, since)
+	if err == nil && byIP >= forgotPasswordIPLimit {
+		return false, errors.New("too many password reset requests, try again later")
+	}
+	byEmail, err := s.Attempts.CountByEmailSince(models.AttemptForgotPassword, email, since)
+	if err == nil && byEmail >= forgotPasswordEmailLimit {
+		return false, nil
+	}
+	if err := s.Attempts.Record(models.AttemptForgotPassword, email, ip); err != nil {
+		log.Println("Failed to record password reset request:", err)
+	}
+	return true, nil
+}
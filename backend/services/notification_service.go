@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"splitwise/mailer"
+	"splitwise/models"
+	"splitwise/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// notificationPageSize caps how many notifications are listed at once.
+const notificationPageSize = 100
+
+type NotificationService struct {
+	Repo     *repository.NotificationRepo
+	UserRepo *repository.UserRepo
+	Mailer   mailer.Mailer
+}
+
+// Notify sends a copy of n to each recipient, skipping whoever caused it and
+// anyone who muted the type, and emails those who opted in. Failures are
+// logged rather than returned so they never undo the action being announced.
+// A nil service does nothing.
+func (s *NotificationService) Notify(recipients []primitive.ObjectID, n models.Notification) {
+	if s == nil {
+		return
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	var notifications []models.Notification
+	var emailTo []primitive.ObjectID
+	for _, uid := range recipients {
+		if seen[uid] || (n.ActorID != nil && *n.ActorID == uid) {
+			continue
+		}
+		seen[uid] = true
+
+		prefs, err := s.Repo.GetPreferences(uid)
+		if err != nil {
+			log.Println("Failed to load notification preferences for", uid.Hex(), err)
+			continue
+		}
+		if containsString(prefs.Muted, n.Type) {
+			continue
+		}
+
+		notification := n
+		notification.UserID = uid
+		notifications = append(notifications, notification)
+		if containsString(prefs.Email, n.Type) {
+			emailTo = append(emailTo, uid)
+		}
+	}
+
+	if err := s.Repo.CreateMany(notifications); err != nil {
+		log.Println("Failed to create", n.Type, "notifications:", err)
+	}
+	if len(emailTo) > 0 && s.Mailer != nil {
+		go s.email(emailTo, n)
+	}
+}
+
+// email sends the notification to each user's address.
+func (s *NotificationService) email(userIDs []primitive.ObjectID, n models.Notification) {
+	for _, uid := range userIDs {
+		user, err := s.UserRepo.GetByID(uid)
+		if err != nil || user.Placeholder {
+			continue
+		}
+		msg, err := mailer.Render("notification", user.Email, n.Message, map[string]string{
+			"Name":    user.Name,
+			"Message": n.Message,
+			"Link":    mailer.BaseURL(),
+		})
+		if err == nil {
+			err = s.Mailer.Send(msg)
+		}
+		if err != nil {
+			log.Println("Failed to email", n.Type, "notification to", uid.Hex(), err)
+		}
+	}
+}
+
+// GetNotifications lists the user's latest notifications.
+func (s *NotificationService) GetNotifications(userID string, unreadOnly bool) ([]models.Notification, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	return s.Repo.GetByUser(uID, unreadOnly, notificationPageSize)
+}
+
+func (s *NotificationService) UnreadCount(userID string) (int64, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, errors.New("invalid user id")
+	}
+	return s.Repo.CountUnread(uID)
+}
+
+func (s *NotificationService) MarkRead(userID string, notificationID string) error {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	nID, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		return errors.New("invalid notification id")
+	}
+
+	if err := s.Repo.MarkRead(nID, uID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("notification not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *NotificationService) MarkAllRead(userID string) error {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	return s.Repo.MarkAllRead(uID)
+}
+
+func (s *NotificationService) GetPreferences(userID string) (*models.NotificationPreferences, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	return s.Repo.GetPreferences(uID)
+}
+
+// UpdatePreferences replaces the notification types the user has muted and
+// the ones they get by email.
+func (s *NotificationService) UpdatePreferences(userID string, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	muted, err := notificationTypeSet(req.Muted)
+	if err != nil {
+		return nil, err
+	}
+	email, err := notificationTypeSet(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := &models.NotificationPreferences{UserID: uID, Muted: muted, Email: email}
+	if err := s.Repo.SetPreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// notificationTypeSet validates and de-duplicates a list of notification types.
+func notificationTypeSet(types []string) ([]string, error) {
+	set := []string{}
+	for _, t := range types {
+		if !containsString(models.NotificationTypes, t) {
+			return nil, errors.New("unknown notification type: " + t)
+		}
+		if !containsString(set, t) {
+			set = append(set, t)
+		}
+	}
+	return set, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// memberIDs returns the user IDs of everyone in the group.
+func memberIDs(group *models.Group) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(group.Members))
+	for _, m := range group.Members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
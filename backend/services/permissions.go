@@ -0,0 +1,128 @@
+package services
+
+import (
+	"splitwise/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group actions that are checked against a member's role.
+const (
+	permUpdateGroup         = "group.update"
+	permDeleteGroup         = "group.delete"
+	permAddMember           = "members.add"
+	permRemoveMember        = "members.remove"
+	permManageRoles         = "members.manage_roles"
+	permManageAdmins        = "members.manage_admins"
+	permAddExpense          = "expenses.add"
+	permEditOwnExpense      = "expenses.edit_own"
+	permEditAnyExpense      = "expenses.edit_any"
+	permDeleteOwnExpense    = "expenses.delete_own"
+	permDeleteAnyExpense    = "expenses.delete_any"
+	permSettle              = "settlements.create"
+	permSettleAll           = "settlements.settle_all"
+	permDeleteOwnSettlement = "settlements.delete_own"
+	permDeleteAnySettlement = "settlements.delete_any"
+	permCreateInvite        = "invites.create"
+	permRevokeAnyInvite     = "invites.revoke_any"
+)
+
+// rolePermissions is the permission matrix for group roles. "Own" expenses
+// are ones the user created (or paid, for expenses that predate CreatedBy);
+// "own" settlements are ones they paid or received.
+var rolePermissions = map[string]map[string]bool{
+	models.RoleOwner: {
+		permUpdateGroup:         true,
+		permDeleteGroup:         true,
+		permAddMember:           true,
+		permRemoveMember:        true,
+		permManageRoles:         true,
+		permManageAdmins:        true,
+		permAddExpense:          true,
+		permEditOwnExpense:      true,
+		permEditAnyExpense:      true,
+		permDeleteOwnExpense:    true,
+		permDeleteAnyExpense:    true,
+		permSettle:              true,
+		permSettleAll:           true,
+		permDeleteOwnSettlement: true,
+		permDeleteAnySettlement: true,
+		permCreateInvite:        true,
+		permRevokeAnyInvite:     true,
+	},
+	models.RoleAdmin: {
+		permUpdateGroup:         true,
+		permAddMember:           true,
+		permRemoveMember:        true,
+		permManageRoles:         true,
+		permAddExpense:          true,
+		permEditOwnExpense:      true,
+		permEditAnyExpense:      true,
+		permDeleteOwnExpense:    true,
+		permDeleteAnyExpense:    true,
+		permSettle:              true,
+		permSettleAll:           true,
+		permDeleteOwnSettlement: true,
+		permDeleteAnySettlement: true,
+		permCreateInvite:        true,
+		permRevokeAnyInvite:     true,
+	},
+	models.RoleMember: {
+		permAddMember:           true,
+		permAddExpense:          true,
+		permEditOwnExpense:      true,
+		permDeleteOwnExpense:    true,
+		permSettle:              true,
+		permDeleteOwnSettlement: true,
+		permCreateInvite:        true,
+	},
+	models.RoleViewer: {},
+}
+
+// can reports whether a member with the given role may perform perm.
+func can(role string, perm string) bool {
+	return rolePermissions[role][perm]
+}
+
+// memberRole returns the user's role in the group, or "" if they aren't a member.
+func memberRole(group *models.Group, userID primitive.ObjectID) string {
+	for _, m := range group.Members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
+
+// helper: check if a userID is in the group's members list
+func isMember(members []models.GroupMember, userID primitive.ObjectID) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// isParticipant reports whether the user can take part in splits and
+// settlements, which excludes read-only viewers.
+func isParticipant(group *models.Group, userID primitive.ObjectID) bool {
+	role := memberRole(group, userID)
+	return role != "" && role != models.RoleViewer
+}
+
+func validRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// isFormerMember reports whether the user was removed from the group with a
+// balance outstanding.
+func isFormerMember(group *models.Group, userID primitive.ObjectID) bool {
+	for _, m := range group.FormerMembers {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
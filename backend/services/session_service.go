@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refreshTokenTTL is how long a session survives without being refreshed.
+// Each refresh pushes the expiry out again.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+type SessionService struct {
+	Repo *repository.SessionRepo
+}
+
+// Start opens a session for the user and returns its first token pair.
+func (s *SessionService) Start(userID primitive.ObjectID, client models.ClientInfo) (*models.TokenPair, error) {
+	refresh, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		UserID:      userID,
+		RefreshHash: utils.HashToken(refresh),
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		ExpiresAt:   time.Now().Add(refreshTokenTTL),
+	}
+	if err := s.Repo.Create(session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+	return s.tokenPair(session, refresh)
+}
+
+// Refresh exchanges a refresh token for a new pair. The old refresh token
+// stops working; presenting it again is treated as theft and ends the
+// session for both holders.
+func (s *SessionService) Refresh(req models.RefreshRequest) (*models.TokenPair, error) {
+	hash := utils.HashToken(req.RefreshToken)
+	session, err := s.Repo.GetByRefreshHash(hash)
+	if err != nil {
+		if reused, err := s.Repo.GetByPreviousHash(hash); err == nil {
+			log.Printf("Refresh token reuse detected for session %s, revoking", reused.ID.Hex())
+			s.Repo.Revoke(reused.ID)
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, errors.New("session has expired, please log in again")
+	}
+
+	refresh, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.Repo.Rotate(session.ID, hash, utils.HashToken(refresh), time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return nil, errors.New("failed to refresh session")
+	}
+	if !ok {
+		return nil, errors.New("invalid refresh token")
+	}
+	return s.tokenPair(session, refresh)
+}
+
+// Logout revokes the session the request was made with.
+func (s *SessionService) Logout(sessionID string) error {
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return errors.New("invalid session id")
+	}
+	return s.Repo.Revoke(objID)
+}
+
+// LogoutAll revokes every session of the user, including the current one.
+func (s *SessionService) LogoutAll(userID string) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	return s.Repo.RevokeAllForUser(objID, nil)
+}
+
+// Validate is the AuthMiddleware hook: it rejects access tokens whose
+// session was revoked or has expired.
+func (s *SessionService) Validate(userID, sessionID string) error {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	sid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	active, err := s.Repo.IsActive(sid, uid)
+	if err != nil || !active {
+		return errors.New("session has been revoked")
+	}
+	return nil
+}
+
+func (s *SessionService) tokenPair(session *models.Session, refresh string) (*models.TokenPair, error) {
+	access, err := utils.GenerateJWT(session.UserID.Hex(), session.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
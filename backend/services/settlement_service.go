@@ -1,18 +1,29 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"time"
 
 	"splitwise/models"
 	"splitwise/repository"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 type SettlementService struct {
 	Repo       *repository.SettlementRepo
+	GroupRepo  *repository.GroupRepo
+	UserRepo   *repository.UserRepo
 	BalanceSvc *BalanceService
+	Notifier   *NotificationService
+	Events     *EventBus
 }
-func (s *SettlementService) Settle(groupID string, req models.SettleRequest) (*models.Settlement, error) {
+// Settle records a payment between two group members. The settlement stays
+// pending until PaidTo confirms it, unless PaidTo is the one recording it.
+func (s *SettlementService) Settle(groupID string, userID string, req models.SettleRequest) (*models.Settlement, error) {
 	gID, err := primitive.ObjectIDFromHex(groupID)
 	if err != nil {
 		return nil, errors.New("invalid group id")
@@ -28,36 +39,388 @@ func (s *SettlementService) Settle(groupID string, req models.SettleRequest) (*m
 		return nil, errors.New("invalid paid to user id")
 	}
 
+	if err := validatePaymentMethod(req.Method, req.App); err != nil {
+		return nil, err
+	}
+
+	paidAt, err := parsePaidAt(req.PaidAt)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	// Auth: viewers can't record settlements
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	if !can(memberRole(group, uID), permSettle) {
+		return nil, errors.New("you do not have permission to record settlements")
+	}
+
+	// Both parties must belong to the group; former members can still settle
+	// what they left owing
+	if !(isParticipant(group, paidBy) || isFormerMember(group, paidBy)) ||
+		!(isParticipant(group, paidTo) || isFormerMember(group, paidTo)) {
+		return nil, errors.New("payer and payee must be members of the group")
+	}
+
+	outstanding, err := s.BalanceSvc.OutstandingBetween(gID, paidBy, paidTo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payments already awaiting confirmation will cover part of the debt
+	pending, err := s.Repo.GetPendingByGroup(gID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pending {
+		if p.PaidBy == paidBy && p.PaidTo == paidTo {
+			outstanding -= p.Amount
+		}
+	}
+	outstanding = math.Max(0, math.Round(outstanding*100)/100)
+
+	amount := req.Amount
+	if req.Full {
+		if outstanding <= 0 {
+			return nil, errors.New("nothing is owed between these users")
+		}
+		amount = outstanding
+	}
+
+	var warning string
+	if amount-outstanding > 0.01 {
+		if group.SettlementPolicy == "reject" {
+			return nil, fmt.Errorf("settlement exceeds the outstanding amount of %.2f", outstanding)
+		}
+		warning = fmt.Sprintf("settlement exceeds the outstanding amount of %.2f", outstanding)
+	}
+
+	// Placeholder users can't log in to confirm, so payments to them stand
+	status := "pending"
+	if userID == req.PaidTo {
+		status = "confirmed"
+	} else if payee, err := s.UserRepo.GetByID(paidTo); err == nil && payee.Placeholder {
+		status = "confirmed"
+	}
+
 	settlement := &models.Settlement{
-		GroupID: gID,
-		PaidBy:  paidBy,
-		PaidTo:  paidTo,
-		Amount:  req.Amount,
+		GroupID:   gID,
+		PaidBy:    paidBy,
+		PaidTo:    paidTo,
+		Amount:    amount,
+		Method:    req.Method,
+		App:       req.App,
+		Reference: req.Reference,
+		Note:      req.Note,
+		PaidAt:    paidAt,
+		Status:    status,
 	}
 
-	if err := s.Repo.CreateSettlement(settlement); err != nil {
+	if status == "confirmed" {
+		err = changeLedger(s.GroupRepo, gID, func(ctx mongo.SessionContext) error {
+			return s.Repo.CreateSettlement(ctx, settlement)
+		})
+	} else {
+		err = s.Repo.CreateSettlement(context.Background(), settlement)
+	}
+	if err != nil {
 		return nil, err
 	}
+	if status == "confirmed" {
+		s.notifySettlement(models.NotifySettlementCreated, settlement, uID,
+			fmt.Sprintf("A payment of %.2f was recorded in %s", amount, group.Name))
+	} else {
+		s.notifySettlement(models.NotifySettlementCreated, settlement, uID,
+			fmt.Sprintf("Confirm a payment of %.2f made to you in %s", amount, group.Name))
+	}
+	settlement.Warning = warning
+	s.Events.PublishToGroup(EventSettlementCreated, group, settlement)
 	return settlement, nil
 }
-func (s *SettlementService) GetGroupSettlements(groupID string) ([]models.Settlement, error) {
+// SettleAll records every transfer in the group's current simplified plan as
+// a confirmed settlement, all in one transaction. It fails if the group's
+// balances change between reading the plan and writing the settlements.
+func (s *SettlementService) SettleAll(groupID string, userID string, req models.SettleAllRequest) ([]models.Settlement, error) {
 	gID, err := primitive.ObjectIDFromHex(groupID)
 	if err != nil {
 		return nil, errors.New("invalid group id")
 	}
-	return s.Repo.GetByGroup(gID)
+
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	group, err := s.GroupRepo.GetByID(gID)
+	if err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	// Auth: only owners and admins can close out the whole group
+	if !can(memberRole(group, uID), permSettleAll) {
+		return nil, errors.New("you do not have permission to settle all debts")
+	}
+
+	// Read the version before the plan so any later change is caught
+	version := group.LedgerVersion
+	if req.LedgerVersion != nil && *req.LedgerVersion != version {
+		return nil, errors.New("group balances changed since the plan was read")
+	}
+
+	pending, err := s.Repo.GetPendingByGroup(gID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, errors.New("confirm or reject pending settlements first")
+	}
+
+	plan, err := s.BalanceSvc.GetGroupBalances(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		return nil, errors.New("group is already settled up")
+	}
+
+	now := time.Now()
+	settlements := make([]models.Settlement, 0, len(plan))
+	for _, transfer := range plan {
+		paidBy, _ := primitive.ObjectIDFromHex(transfer.FromUserID)
+		paidTo, _ := primitive.ObjectIDFromHex(transfer.ToUser)
+		settlements = append(settlements, models.Settlement{
+			GroupID:     gID,
+			PaidBy:      paidBy,
+			PaidTo:      paidTo,
+			Amount:      transfer.Amount,
+			PaidAt:      now,
+			Status:      "confirmed",
+			RespondedAt: &now,
+		})
+	}
+
+	errLedgerChanged := errors.New("group balances changed since the plan was read")
+	err = repository.WithTransaction(func(ctx mongo.SessionContext) error {
+		if err := s.Repo.CreateMany(ctx, settlements); err != nil {
+			return err
+		}
+		advanced, err := s.GroupRepo.AdvanceLedgerVersion(ctx, gID, version)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return errLedgerChanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range settlements {
+		s.notifySettlement(models.NotifySettlementCreated, &settlements[i], uID,
+			fmt.Sprintf("Settle all recorded a payment of %.2f in %s", settlements[i].Amount, group.Name))
+		s.Events.PublishToGroup(EventSettlementCreated, group, settlements[i])
+	}
+
+	if req.Archive {
+		if err := s.GroupRepo.SetArchived(gID, true); err != nil {
+			return nil, err
+		}
+	}
+	return settlements, nil
 }
-func (s *SettlementService) GetUserSettlements(userID string) ([]models.Settlement, error) {
+
+// notifySettlement tells both sides of a settlement about it, except actor.
+func (s *SettlementService) notifySettlement(kind string, settlement *models.Settlement, actor primitive.ObjectID, message string) {
+	s.Notifier.Notify([]primitive.ObjectID{settlement.PaidBy, settlement.PaidTo}, models.Notification{
+		Type:    kind,
+		Message: message,
+		ActorID: &actor,
+		GroupID: &settlement.GroupID,
+		RefID:   &settlement.ID,
+	})
+}
+// validatePaymentMethod checks the method against the supported ones; an
+// empty method is allowed for callers that don't track it.
+func validatePaymentMethod(method string, app string) error {
+	switch method {
+	case "", "cash", "bank_transfer":
+		if app != "" {
+			return errors.New("app can only be set when method is app")
+		}
+		return nil
+	case "app":
+		if app == "" {
+			return errors.New("app is required when method is app")
+		}
+		return nil
+	default:
+		return errors.New("method must be cash, bank_transfer or app")
+	}
+}
+
+// parsePaidAt accepts RFC 3339 timestamps or plain dates; empty means now.
+func parsePaidAt(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		return time.Time{}, errors.New("paid_at must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if t.After(time.Now()) {
+		return time.Time{}, errors.New("paid_at cannot be in the future")
+	}
+	return t, nil
+}
+
+func (s *SettlementService) GetGroupSettlements(groupID string, method string) ([]models.Settlement, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+	return s.Repo.GetByGroup(gID, method)
+}
+func (s *SettlementService) GetPendingSettlements(groupID string) ([]models.Settlement, error) {
+	gID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid group id")
+	}
+	return s.Repo.GetPendingByGroup(gID)
+}
+
+// ConfirmSettlement lets the payee acknowledge that the payment arrived.
+func (s *SettlementService) ConfirmSettlement(userID string, settlementID string) error {
+	settlement, err := s.pendingForPayee(userID, settlementID, "you can only confirm settlements paid to you")
+	if err != nil {
+		return err
+	}
+	err = changeLedger(s.GroupRepo, settlement.GroupID, func(ctx mongo.SessionContext) error {
+		return s.Repo.UpdateStatus(ctx, settlement.ID, "confirmed", "")
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("settlement is no longer pending")
+		}
+		return err
+	}
+	s.notifySettlement(models.NotifySettlementConfirmed, settlement, settlement.PaidTo,
+		fmt.Sprintf("Your payment of %.2f was confirmed", settlement.Amount))
+	settlement.Status = "confirmed"
+	s.publishSettlement(EventSettlementConfirmed, settlement)
+	return nil
+}
+
+// RejectSettlement lets the payee dispute a payment they never received.
+func (s *SettlementService) RejectSettlement(userID string, settlementID string, req models.RejectSettlementRequest) error {
+	settlement, err := s.pendingForPayee(userID, settlementID, "you can only reject settlements paid to you")
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.UpdateStatus(context.Background(), settlement.ID, "rejected", req.Reason); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("settlement is no longer pending")
+		}
+		return err
+	}
+	s.notifySettlement(models.NotifySettlementRejected, settlement, settlement.PaidTo,
+		fmt.Sprintf("Your payment of %.2f was rejected", settlement.Amount))
+	settlement.Status = "rejected"
+	settlement.RejectReason = req.Reason
+	s.publishSettlement(EventSettlementRejected, settlement)
+	return nil
+}
+
+// publishSettlement announces a settlement change to its group.
+func (s *SettlementService) publishSettlement(kind string, settlement *models.Settlement) {
+	if s.Events == nil {
+		return
+	}
+	group, err := s.GroupRepo.GetByID(settlement.GroupID)
+	if err != nil {
+		return
+	}
+	s.Events.PublishToGroup(kind, group, settlement)
+}
+
+func (s *SettlementService) pendingForPayee(userID string, settlementID string, forbidden string) (*models.Settlement, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	sID, err := primitive.ObjectIDFromHex(settlementID)
+	if err != nil {
+		return nil, errors.New("invalid settlement id")
+	}
+
+	settlement, err := s.Repo.GetByID(sID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("settlement not found")
+		}
+		return nil, err
+	}
+
+	if settlement.PaidTo != uID {
+		return nil, errors.New(forbidden)
+	}
+
+	if settlement.Status != "pending" {
+		return nil, errors.New("this settlement is not pending")
+	}
+	return settlement, nil
+}
+func (s *SettlementService) GetUserSettlements(userID string, method string) ([]models.Settlement, error) {
 	uID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user id")
 	}
-	return s.Repo.GetByUser(uID)
+	return s.Repo.GetByUser(uID, method)
 }
-func (s *SettlementService) DeleteSettlement(settlementID string) error {
+// DeleteSettlement removes a settlement. Members can delete settlements they
+// paid or received; owners and admins can delete any.
+func (s *SettlementService) DeleteSettlement(settlementID string, userID string) error {
 	objID, err := primitive.ObjectIDFromHex(settlementID)
 	if err != nil {
 		return errors.New("invalid settlement id")
 	}
-	return s.Repo.DeleteSettlement(objID)
-}
\ No newline at end of file
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	settlement, err := s.Repo.GetByID(objID)
+	if err != nil {
+		return errors.New("settlement not found")
+	}
+	group, err := s.GroupRepo.GetByID(settlement.GroupID)
+	if err != nil {
+		return errors.New("group not found")
+	}
+	perm := permDeleteAnySettlement
+	if settlement.PaidBy == uID || settlement.PaidTo == uID {
+		perm = permDeleteOwnSettlement
+	}
+	if !can(memberRole(group, uID), perm) {
+		return errors.New("you do not have permission to delete this settlement")
+	}
+	err = changeLedger(s.GroupRepo, settlement.GroupID, func(ctx mongo.SessionContext) error {
+		return s.Repo.DeleteSettlement(ctx, objID)
+	})
+	if err != nil {
+		return err
+	}
+	s.Events.PublishToGroup(EventSettlementDeleted, group, settlement)
+	return nil
+}
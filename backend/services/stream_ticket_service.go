@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// streamTicketTTL only has to cover the gap between asking for a ticket and
+// opening the stream.
+const streamTicketTTL = 30 * time.Second
+
+type StreamTicketService struct {
+	Repo *repository.StreamTicketRepo
+}
+
+// Issue creates a single-use ticket for the caller's event stream. sessionID
+// is empty when the caller authenticated with an access token.
+func (s *StreamTicketService) Issue(userID, sessionID string) (*models.StreamTicketResponse, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	raw, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	ticket := &models.StreamTicket{
+		UserID:     uID,
+		TicketHash: utils.HashToken(raw),
+		ExpiresAt:  time.Now().Add(streamTicketTTL),
+	}
+	if sID, err := primitive.ObjectIDFromHex(sessionID); err == nil {
+		ticket.SessionID = &sID
+	}
+	if err := s.Repo.Create(ticket); err != nil {
+		return nil, err
+	}
+	return &models.StreamTicketResponse{Ticket: raw, ExpiresIn: int(streamTicketTTL.Seconds())}, nil
+}
+
+// Redeem uses up a ticket and returns the user and session it was issued to.
+func (s *StreamTicketService) Redeem(raw string) (string, string, error) {
+	ticket, err := s.Repo.Consume(utils.HashToken(raw))
+	if err != nil {
+		return "", "", errors.New("invalid or expired stream ticket")
+	}
+	sessionID := ""
+	if ticket.SessionID != nil {
+		sessionID = ticket.SessionID.Hex()
+	}
+	return ticket.UserID.Hex(), sessionID, nil
+}
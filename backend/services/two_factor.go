@@ -0,0 +1,228 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"splitwise/models"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// loginChallengeExpiry bounds the gap between the password and code steps.
+	loginChallengeExpiry = 5 * time.Minute
+	// loginChallengeAttempts is how many wrong codes end a challenge.
+	loginChallengeAttempts = 5
+	recoveryCodeCount      = 10
+	totpIssuer             = "Splitwise"
+)
+
+// startLogin finishes a password login: it opens a session, or for accounts
+// with 2FA hands out a challenge to be completed by LoginTwoFactor.
+func (s *UserService) startLogin(user *models.User, client models.ClientInfo) (*models.LoginResult, error) {
+	if !user.TwoFactorEnabled {
+		tokens, err := s.Sessions.Start(user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{TokenPair: tokens}, nil
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := &models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(loginChallengeExpiry),
+	}
+	if err := s.Repo.CreateLoginChallenge(challenge); err != nil {
+		return nil, errors.New("failed to start login")
+	}
+	return &models.LoginResult{TwoFactorRequired: true, ChallengeToken: token}, nil
+}
+
+// LoginTwoFactor completes a login challenge with a TOTP or recovery code.
+func (s *UserService) LoginTwoFactor(req models.TwoFactorLoginRequest, client models.ClientInfo) (*models.TokenPair, error) {
+	hash := utils.HashToken(req.ChallengeToken)
+	challenge, err := s.Repo.ClaimChallengeAttempt(hash, loginChallengeAttempts)
+	if err != nil {
+		if spent, err := s.Repo.GetLoginChallengeByHash(hash); err == nil {
+			s.Repo.DeleteLoginChallenge(spent.ID)
+			return nil, errors.New("login challenge has expired, please log in again")
+		}
+		return nil, errors.New("invalid or expired login challenge")
+	}
+
+	user, err := s.Repo.GetByID(challenge.UserID)
+	if err != nil {
+		return nil, errors.New("invalid or expired login challenge")
+	}
+	// Wrong codes count against the same limits as wrong passwords.
+	email := attemptKey(user.Email)
+	if err := s.checkLoginAllowed(email, client.IP, user); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(user, req.Code); err != nil {
+		s.recordLoginFailure(email, client.IP, user)
+		return nil, err
+	}
+	s.recordLoginSuccess(email)
+
+	s.Repo.DeleteLoginChallenge(challenge.ID)
+	return s.Sessions.Start(user.ID, client)
+}
+
+// SetupTwoFactor starts enrollment with a new secret. 2FA isn't on until
+// ConfirmTwoFactor sees a code from the app.
+func (s *UserService) SetupTwoFactor(userID string) (*models.TwoFactorSetup, error) {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.SetPendingTOTP(user.ID, secret); err != nil {
+		return nil, errors.New("failed to start two-factor setup")
+	}
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor turns 2FA on and returns the recovery codes.
+func (s *UserService) ConfirmTwoFactor(userID string, req models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, errors.New("start two-factor setup first")
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPPendingSecret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.EnableTOTP(user.ID, user.TOTPPendingSecret, step, hashes); err != nil {
+		return nil, errors.New("failed to enable two-factor authentication")
+	}
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns 2FA off. It takes both the password and a code so
+// a stolen session alone can't remove the second factor.
+func (s *UserService) DisableTwoFactor(userID string, req models.DisableTwoFactorRequest) error {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		return errors.New("incorrect password")
+	}
+	if err := s.checkSecondFactor(user, req.Code); err != nil {
+		return err
+	}
+	return s.Repo.DisableTOTP(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not.
+func (s *UserService) RegenerateRecoveryCodes(userID string, req models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	user, err := s.userByHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if err := s.checkSecondFactor(user, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.SetRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, errors.New("failed to regenerate recovery codes")
+	}
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// checkSecondFactor accepts a TOTP code that hasn't been used yet, or
+// consumes a recovery code.
+func (s *UserService) checkSecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.Repo.UseTOTPStep(user.ID, step)
+		if err != nil || !fresh {
+			return errors.New("invalid two-factor code")
+		}
+		return nil
+	}
+
+	used, err := s.Repo.UseRecoveryCode(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil || !used {
+		return errors.New("invalid two-factor code")
+	}
+	return nil
+}
+
+func (s *UserService) userByHex(userID string) (*models.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	user, err := s.Repo.GetByID(objID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// newRecoveryCodes returns codes formatted for display (xxxxx-xxxxx) along
+// with the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(bytes)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = utils.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -1,25 +1,41 @@
 package services
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
 	"time"
 
+	"splitwise/mailer"
 	"splitwise/models"
 	"splitwise/repository"
 	"splitwise/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserService struct {
-	Repo *repository.UserRepo
+	Repo           *repository.UserRepo
+	GroupRepo      *repository.GroupRepo
+	ExpenseRepo    *repository.ExpenseRepo
+	SettlementRepo *repository.SettlementRepo
+	FriendRepo     *repository.FriendRepo
+	NotifyRepo     *repository.NotificationRepo
+	TokenRepo      *repository.AccessTokenRepo
+	WebhookRepo    *repository.WebhookRepo
+	ExportRepo     *repository.ExportRepo
+	Attempts       *repository.LoginAttemptRepo
+	GroupSvc       *GroupService
+	Sessions       *SessionService
+	Mailer         mailer.Mailer
 }
 
+// Register creates an account. Only a verified account holds its email; while
+// nobody has verified it, several accounts can sign up with the same address
+// and whichever verifies it first keeps it.
 func (s *UserService) Register(req models.RegisterRequest) (*models.User, error) {
-	existing, _ := s.Repo.GetByEmail(req.Email)
-	if existing != nil {
+	if existing, err := s.Repo.GetByEmail(req.Email); err == nil && !existing.Placeholder && existing.EmailVerified {
 		return nil, errors.New("email already in use")
 	}
 
@@ -37,23 +53,210 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.User, error)
 	if err := s.Repo.CreateUser(user); err != nil {
 		return nil, err
 	}
+
+	// Any placeholder for this email is merged once the address is verified
+	go func() {
+		if err := s.sendVerification(user); err != nil {
+			log.Println("Failed to send verification email to", user.ID.Hex(), ":", err)
+		}
+	}()
 	return user, nil
 }
-func (s *UserService) Login(req models.LoginRequest) (string, error) {
-	user, err := s.Repo.GetByEmail(req.Email)
+
+// Verification emails can be resent at most once a minute and five times a day.
+const (
+	verificationExpiry      = 24 * time.Hour
+	verificationResendDelay = time.Minute
+	verificationDailyLimit  = 5
+)
+
+// ResendVerification emails the user a new verification link.
+func (s *UserService) ResendVerification(userID string) error {
+	uID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return errors.New("invalid user id")
 	}
 
-	if !utils.CheckPassword(req.Password, user.Password) {
-		return "", errors.New("invalid email or password")
+	user, err := s.Repo.GetByID(uID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.EmailVerified {
+		return errors.New("email is already verified")
 	}
 
-	token, err := utils.GenerateJWT(user.ID.Hex())
+	if latest, err := s.Repo.GetLatestEmailVerification(uID); err == nil && time.Since(latest.CreatedAt) < verificationResendDelay {
+		return errors.New("please wait a minute before requesting another verification email")
+	}
+	sent, err := s.Repo.CountEmailVerificationsSince(uID, time.Now().Add(-24*time.Hour))
 	if err != nil {
-		return "", err
+		return err
+	}
+	if sent >= verificationDailyLimit {
+		return errors.New("too many verification emails requested; try again tomorrow")
 	}
-	return token, nil
+
+	return s.sendVerification(user)
+}
+
+func (s *UserService) sendVerification(user *models.User) error {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: time.Now().Add(verificationExpiry),
+	}
+	if err := s.Repo.CreateEmailVerification(verification); err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render("email_verification", user.Email, "Verify your Splitwise email", map[string]string{
+		"Name":      user.Name,
+		"Link":      mailer.BaseURL() + "/verify-email?token=" + url.QueryEscape(token),
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(msg)
+}
+
+// VerifyEmail marks the account's email as verified, releases other accounts
+// that signed up with it without verifying, and takes over the history of
+// any placeholder that was added with that email. Tokens from ChangeEmail
+// move the account to the new address instead.
+func (s *UserService) VerifyEmail(req models.VerifyEmailRequest) error {
+	verification, err := s.Repo.GetEmailVerificationByToken(req.Token)
+	if err != nil || time.Now().After(verification.ExpiresAt) {
+		return errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.Repo.GetByID(verification.UserID)
+	if err != nil {
+		return errors.New("invalid or expired verification token")
+	}
+	if verification.EmailChange {
+		return s.completeEmailChange(user, verification)
+	}
+	if user.Email != verification.Email || user.DeletionStartedAt != nil {
+		return errors.New("invalid or expired verification token")
+	}
+	if existing, err := s.Repo.GetByEmail(user.Email); err == nil && existing.ID != user.ID &&
+		!existing.Placeholder && existing.EmailVerified {
+		return errors.New("email already in use")
+	}
+
+	if err := s.Repo.SetEmailVerified(user.ID); err != nil {
+		return err
+	}
+	s.Repo.DeleteEmailVerifications(user.ID)
+	s.releaseEmail(user.Email, user.ID)
+
+	if placeholder, err := s.Repo.GetPlaceholderByEmail(user.Email); err == nil {
+		if err := s.mergePlaceholder(placeholder.ID, user.ID); err != nil {
+			log.Println("Failed to merge placeholder user", placeholder.ID.Hex(), "into", user.ID.Hex(), ":", err)
+		}
+	}
+	return nil
+}
+
+// releaseEmail deletes the other accounts that signed up with email but
+// never verified it, now that owner has proven the address is theirs. They
+// go through the same steps as a deleted account; a step that fails is
+// retried by RunPendingDeletions.
+func (s *UserService) releaseEmail(email string, owner primitive.ObjectID) {
+	users, err := s.Repo.GetAccountsByEmail(email)
+	if err != nil {
+		log.Println("Failed to list unverified accounts to release:", err)
+		return
+	}
+	for i := range users {
+		if users[i].ID == owner || users[i].EmailVerified {
+			continue
+		}
+		log.Println("Releasing email of unverified account", users[i].ID.Hex())
+		if err := s.Repo.MarkDeletionStarted(users[i].ID); err != nil {
+			log.Println("Failed to release unverified account", users[i].ID.Hex(), ":", err)
+			continue
+		}
+		if err := s.finishDeletion(&users[i]); err != nil {
+			log.Println("Releasing unverified account", users[i].ID.Hex(), "stopped, will retry:", err)
+		}
+	}
+}
+
+// mergePlaceholder points every group, expense, settlement, friendship and
+// notification reference from the placeholder at the real account, then deletes the
+// placeholder.
+func (s *UserService) mergePlaceholder(placeholderID, userID primitive.ObjectID) error {
+	return repository.WithTransaction(func(ctx mongo.SessionContext) error {
+		if err := s.GroupRepo.ReplaceUser(ctx, placeholderID, userID); err != nil {
+			return err
+		}
+		if err := s.ExpenseRepo.ReplaceUser(ctx, placeholderID, userID); err != nil {
+			return err
+		}
+		if err := s.SettlementRepo.ReplaceUser(ctx, placeholderID, userID); err != nil {
+			return err
+		}
+		if err := s.FriendRepo.ReplaceUser(ctx, placeholderID, userID); err != nil {
+			return err
+		}
+		if err := s.NotifyRepo.ReplaceUser(ctx, placeholderID, userID); err != nil {
+			return err
+		}
+		return s.Repo.DeleteUser(ctx, placeholderID)
+	})
+}
+
+// Login checks the password. Accounts with 2FA get a challenge instead of
+// tokens; see LoginTwoFactor. Failed attempts are throttled per account and
+// per IP, and repeated failures lock the account.
+func (s *UserService) Login(req models.LoginRequest, client models.ClientInfo) (*models.LoginResult, error) {
+	email := attemptKey(req.Email)
+	// Until someone verifies the address several accounts can share it, and
+	// the password tells them apart. A verified account is the only one.
+	var candidates []models.User
+	accounts, _ := s.Repo.GetAccountsByEmail(req.Email)
+	for _, account := range accounts {
+		if account.DeletionStartedAt == nil {
+			candidates = append(candidates, account)
+		}
+	}
+	if len(candidates) > 0 && candidates[0].EmailVerified {
+		candidates = candidates[:1]
+	}
+	var first *models.User
+	if len(candidates) > 0 {
+		first = &candidates[0]
+	}
+
+	if err := s.checkLoginAllowed(email, client.IP, first); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	for i := range candidates {
+		if utils.CheckPassword(req.Password, candidates[i].Password) {
+			user = &candidates[i]
+			break
+		}
+	}
+	if user == nil {
+		s.recordLoginFailure(email, client.IP, first)
+		return nil, errors.New("invalid email or password")
+	}
+
+	s.upgradePasswordHash(req.Password, user)
+	if !user.TwoFactorEnabled {
+		s.recordLoginSuccess(email)
+	}
+	return s.startLogin(user, client)
 }
 func (s *UserService) GetProfile(userID string) (*models.User, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
@@ -82,23 +285,32 @@ func (s *UserService) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
-func generateResetToken() (string, error) {
-	bytes := make([]byte, 32)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// ForgotPassword emails a reset link to the account's address. It does
+// nothing for unknown emails, and the email goes out in the background, so
+// callers can't tell from the result or timing whether an account exists.
+// The only error is the per-IP request limit.
+func (s *UserService) ForgotPassword(req models.ForgotPasswordRequest, ip string) error {
+	allowed, err := s.allowPasswordReset(attemptKey(req.Email), ip)
+	if err != nil || !allowed {
+		return err
 	}
-	return hex.EncodeToString(bytes), nil
+	go func() {
+		if err := s.sendPasswordReset(req.Email); err != nil {
+			log.Println("Failed to send password reset email:", err)
+		}
+	}()
+	return nil
 }
 
-func (s *UserService) ForgotPassword(req models.ForgotPasswordRequest) (string, error) {
-	user, err := s.Repo.GetByEmail(req.Email)
-	if err != nil {
-		return "", errors.New("no account found with that email")
+func (s *UserService) sendPasswordReset(email string) error {
+	user, err := s.Repo.GetByEmail(email)
+	if err != nil || user.Placeholder {
+		return nil
 	}
 
-	token, err := generateResetToken()
+	token, err := utils.GenerateToken()
 	if err != nil {
-		return "", errors.New("failed to generate reset token")
+		return err
 	}
 
 	reset := &models.PasswordReset{
@@ -108,10 +320,18 @@ func (s *UserService) ForgotPassword(req models.ForgotPasswordRequest) (string,
 	}
 
 	if err := s.Repo.CreatePasswordReset(reset); err != nil {
-		return "", errors.New("failed to create reset token")
+		return err
 	}
 
-	return token, nil
+	msg, err := mailer.Render("password_reset", user.Email, "Reset your Splitwise password", map[string]string{
+		"Name":      user.Name,
+		"Link":      mailer.BaseURL() + "/reset-password?token=" + url.QueryEscape(token),
+		"ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(msg)
 }
 
 func (s *UserService) ResetPassword(req models.ResetPasswordRequest) error {
@@ -141,5 +361,16 @@ func (s *UserService) ResetPassword(req models.ResetPasswordRequest) error {
 	// Clean up the used token
 	s.Repo.DeletePasswordReset(reset.ID)
 
+	// Whoever knew the old password may still be signed in
+	if err := s.Sessions.Repo.RevokeAllForUser(reset.UserID, nil); err != nil {
+		log.Println("Failed to revoke sessions after password reset:", err)
+	}
+
+	// A reset proves control of the email, so it also lifts a lockout
+	s.Repo.Unlock(reset.UserID)
+	if user, err := s.Repo.GetByID(reset.UserID); err == nil {
+		s.Attempts.ClearByEmail(models.AttemptLogin, attemptKey(user.Email))
+	}
+
 	return nil
 }
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxWebhookRedirects is how many redirects a delivery follows.
+const maxWebhookRedirects = 3
+
+var defaultWebhookClient = NewWebhookClient(10 * time.Second)
+
+var errWebhookPrivateAddress = errors.New("url must not point at a private, loopback or link-local address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// net.IP.IsPrivate doesn't cover.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedWebhookIP reports whether ip is somewhere webhooks must not reach:
+// this host, the internal network or cloud metadata services.
+func blockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkWebhookHost rejects a webhook URL whose host is, or resolves to, a
+// blocked address. The dialer checks again on every delivery, since DNS can
+// change after the webhook is created.
+func checkWebhookHost(target *url.URL) error {
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedWebhookIP(ip) {
+			return errWebhookPrivateAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(context.Background(), host)
+	if err != nil || len(addrs) == 0 {
+		return errors.New("url host could not be resolved")
+	}
+	for _, addr := range addrs {
+		if blockedWebhookIP(addr.IP) {
+			return errWebhookPrivateAddress
+		}
+	}
+	return nil
+}
+
+// NewWebhookClient returns the HTTP client webhook deliveries are sent with.
+// It refuses to connect to blocked addresses, checking the IP actually
+// dialled so DNS rebinding can't get around it, ignores proxy settings and
+// follows at most a few redirects.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedWebhookIP(ip) {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxWebhookRedirects {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+}
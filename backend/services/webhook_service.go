@@ -0,0 +1,222 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"splitwise/models"
+	"splitwise/pubsub"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookDeliveryLogSize caps how many deliveries are listed per webhook.
+const webhookDeliveryLogSize = 50
+
+// webhookEventTypes are the events webhooks can subscribe to.
+var webhookEventTypes = []string{
+	EventExpenseCreated,
+	EventExpenseUpdated,
+	EventExpenseDeleted,
+	EventSettlementCreated,
+	EventSettlementConfirmed,
+	EventSettlementRejected,
+	EventSettlementDeleted,
+	EventMemberAdded,
+	EventMemberRemoved,
+	EventMemberUpdated,
+}
+
+type WebhookService struct {
+	Repo      *repository.WebhookRepo
+	GroupRepo *repository.GroupRepo
+	Worker    *WebhookWorker
+}
+
+// CreateWebhook registers a webhook for the caller, or for a group if
+// GroupID is set. Group webhooks need owner or admin rights. The secret is
+// only ever returned here.
+func (s *WebhookService) CreateWebhook(userID string, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	if err := checkWebhookHost(target); err != nil {
+		return nil, err
+	}
+
+	for _, kind := range req.Events {
+		if !containsString(webhookEventTypes, kind) {
+			return nil, errors.New("unknown event type: " + kind)
+		}
+	}
+
+	hook := &models.Webhook{
+		OwnerID: uID,
+		URL:     target.String(),
+		Events:  req.Events,
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	if req.GroupID != "" {
+		gID, err := primitive.ObjectIDFromHex(req.GroupID)
+		if err != nil {
+			return nil, errors.New("invalid group id")
+		}
+		group, err := s.GroupRepo.GetByID(gID)
+		if err != nil {
+			return nil, errors.New("group not found")
+		}
+		if !can(memberRole(group, uID), permUpdateGroup) {
+			return nil, errors.New("you do not have permission to add webhooks to this group")
+		}
+		hook.GroupID = &gID
+	}
+
+	hook.Secret, err = utils.GenerateToken()
+	if err != nil {
+		return nil, errors.New("failed to generate webhook secret")
+	}
+
+	if err := s.Repo.Create(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// GetWebhooks lists the caller's webhooks without their secrets.
+func (s *WebhookService) GetWebhooks(userID string) ([]models.Webhook, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	hooks, err := s.Repo.GetByOwner(uID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(userID string, webhookID string) error {
+	hook, err := s.ownedWebhook(userID, webhookID)
+	if err != nil {
+		return err
+	}
+	return s.Repo.Delete(hook.ID)
+}
+
+// GetDeliveries returns the webhook's recent delivery log.
+func (s *WebhookService) GetDeliveries(userID string, webhookID string) ([]models.WebhookDelivery, error) {
+	hook, err := s.ownedWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.GetDeliveries(hook.ID, webhookDeliveryLogSize)
+}
+
+// Ping sends a test event to the webhook straight away and returns the
+// outcome. A failed ping is retried like any other delivery.
+func (s *WebhookService) Ping(userID string, webhookID string) (*models.WebhookDelivery, error) {
+	hook, err := s.ownedWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":       "ping",
+		"webhook_id": hook.ID,
+		"created_at": time.Now(),
+	})
+	delivery := &models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		EventType:     "ping",
+		Payload:       string(payload),
+		Status:        "pending",
+		NextAttemptAt: time.Now().Add(webhookLease),
+	}
+	if err := s.Repo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	s.Worker.Attempt(delivery)
+	return delivery, nil
+}
+
+// HandleEvent queues a delivery of event for every matching webhook whose
+// owner can see it. It is registered with EventBus.OnPublish.
+func (s *WebhookService) HandleEvent(event pubsub.Event) {
+	var recipients []primitive.ObjectID
+	for _, hex := range event.Recipients {
+		if uid, err := primitive.ObjectIDFromHex(hex); err == nil {
+			recipients = append(recipients, uid)
+		}
+	}
+
+	var groupID *primitive.ObjectID
+	if gID, err := primitive.ObjectIDFromHex(event.GroupID); err == nil {
+		groupID = &gID
+	}
+
+	hooks, err := s.Repo.FindMatching(event.Type, groupID, recipients)
+	if err != nil {
+		log.Println("Failed to find webhooks for", event.Type, "event:", err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Failed to encode", event.Type, "webhook payload:", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		// Owners who have left the group stop receiving its events
+		if !containsString(event.Recipients, hook.OwnerID.Hex()) {
+			continue
+		}
+		delivery := &models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        "pending",
+			NextAttemptAt: time.Now(),
+		}
+		if err := s.Repo.CreateDelivery(delivery); err != nil {
+			log.Println("Failed to queue webhook delivery for", hook.ID.Hex(), err)
+		}
+	}
+}
+
+func (s *WebhookService) ownedWebhook(userID string, webhookID string) (*models.Webhook, error) {
+	uID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	wID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, errors.New("invalid webhook id")
+	}
+
+	hook, err := s.Repo.GetByID(wID)
+	if err != nil || hook.OwnerID != uID {
+		return nil, errors.New("webhook not found")
+	}
+	return hook, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"splitwise/models"
+	"splitwise/repository"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Webhook delivery defaults, used when the worker's fields are left zero.
+const (
+	defaultWebhookAttempts  = 8
+	defaultWebhookBaseDelay = 30 * time.Second
+	maxWebhookDelay         = time.Hour
+	webhookPollInterval     = 5 * time.Second
+	webhookLease            = time.Minute
+)
+
+// WebhookWorker sends queued webhook deliveries, retrying failures with
+// exponential backoff until MaxAttempts is reached. Client defaults to
+// NewWebhookClient; tests can swap in one that reaches a local server.
+type WebhookWorker struct {
+	Repo        *repository.WebhookRepo
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// Run processes due deliveries until ctx is cancelled.
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		w.ProcessDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue attempts every delivery that is currently due and returns how
+// many it attempted.
+func (w *WebhookWorker) ProcessDue() int {
+	attempted := 0
+	for {
+		delivery, err := w.Repo.ClaimDue(webhookLease)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Failed to claim webhook delivery:", err)
+			}
+			return attempted
+		}
+		w.Attempt(delivery)
+		attempted++
+	}
+}
+
+// Attempt makes one delivery attempt and records the outcome.
+func (w *WebhookWorker) Attempt(delivery *models.WebhookDelivery) {
+	hook, err := w.Repo.GetByID(delivery.WebhookID)
+	if err != nil {
+		delivery.Status = "failed"
+		delivery.LastError = "webhook no longer exists"
+	} else {
+		delivery.StatusCode, err = w.Send(hook, delivery)
+		w.record(delivery, err)
+	}
+
+	if err := w.Repo.UpdateDelivery(delivery); err != nil {
+		log.Println("Failed to save webhook delivery", delivery.ID.Hex(), err)
+	}
+}
+
+// record updates the delivery after an attempt, scheduling a retry if it
+// failed and attempts remain.
+func (w *WebhookWorker) record(delivery *models.WebhookDelivery, sendErr error) {
+	delivery.Attempts++
+	if sendErr == nil {
+		now := time.Now()
+		delivery.Status = "delivered"
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = sendErr.Error()
+	maxAttempts := w.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultWebhookAttempts
+	}
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = "failed"
+		return
+	}
+	delivery.Status = "pending"
+	delivery.NextAttemptAt = time.Now().Add(w.backoff(delivery.Attempts))
+}
+
+// backoff is the wait before retry number attempts: BaseDelay doubled for
+// each earlier failure, capped at an hour.
+func (w *WebhookWorker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay
+	if delay == 0 {
+		delay = defaultWebhookBaseDelay
+	}
+	for i := 1; i < attempts && delay < maxWebhookDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookDelay {
+		delay = maxWebhookDelay
+	}
+	return delay
+}
+
+// Send posts the delivery's payload to the webhook URL, signed with the
+// webhook's secret. Any non-2xx response counts as a failure.
+func (w *WebhookWorker) Send(hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	client := w.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+
+	payload := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Splitwise-Webhooks/1.0")
+	req.Header.Set("X-Splitwise-Event", delivery.EventType)
+	req.Header.Set("X-Splitwise-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Splitwise-Timestamp", fmt.Sprint(timestamp))
+	req.Header.Set("X-Splitwise-Signature", "sha256="+utils.SignWebhook(hook.Secret, timestamp, payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("endpoint responded with " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"splitwise/models"
+	"splitwise/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testDelivery() *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: primitive.NewObjectID(),
+		EventType: EventExpenseCreated,
+		Payload:   `{"type":"expense.created"}`,
+		Status:    "pending",
+	}
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	worker := &WebhookWorker{Client: srv.Client()}
+	hook := &models.Webhook{URL: srv.URL, Secret: "s3cret"}
+	delivery := testDelivery()
+
+	code, err := worker.Send(hook, delivery)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if code != http.StatusNoContent {
+		t.Fatalf("status code = %d, want %d", code, http.StatusNoContent)
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("body = %q, want %q", body, delivery.Payload)
+	}
+	if h := got.Header.Get("X-Splitwise-Event"); h != delivery.EventType {
+		t.Errorf("X-Splitwise-Event = %q, want %q", h, delivery.EventType)
+	}
+	if h := got.Header.Get("X-Splitwise-Delivery"); h != delivery.ID.Hex() {
+		t.Errorf("X-Splitwise-Delivery = %q, want %q", h, delivery.ID.Hex())
+	}
+	timestamp, err := strconv.ParseInt(got.Header.Get("X-Splitwise-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("X-Splitwise-Timestamp: %v", err)
+	}
+	want := "sha256=" + utils.SignWebhook(hook.Secret, timestamp, body)
+	if h := got.Header.Get("X-Splitwise-Signature"); h != want {
+		t.Errorf("X-Splitwise-Signature = %q, want %q", h, want)
+	}
+}
+
+func TestRetriesWithBackoffUntilDelivered(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	worker := &WebhookWorker{Client: srv.Client(), MaxAttempts: 5, BaseDelay: time.Second}
+	hook := &models.Webhook{URL: srv.URL, Secret: "s3cret"}
+	delivery := testDelivery()
+
+	for i, wantDelay := range []time.Duration{time.Second, 2 * time.Second} {
+		before := time.Now()
+		code, err := worker.Send(hook, delivery)
+		if err == nil {
+			t.Fatalf("attempt %d: expected an error for a 500 response", i+1)
+		}
+		delivery.StatusCode = code
+		worker.record(delivery, err)
+
+		if delivery.Status != "pending" {
+			t.Fatalf("attempt %d: status = %q, want pending", i+1, delivery.Status)
+		}
+		if delivery.StatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Errorf("attempt %d: status code %d, error %q", i+1, delivery.StatusCode, delivery.LastError)
+		}
+		delay := delivery.NextAttemptAt.Sub(before)
+		if delay < wantDelay || delay > wantDelay+time.Second {
+			t.Errorf("attempt %d: retry in %v, want about %v", i+1, delay, wantDelay)
+		}
+	}
+
+	code, err := worker.Send(hook, delivery)
+	delivery.StatusCode = code
+	worker.record(delivery, err)
+	if delivery.Status != "delivered" || delivery.DeliveredAt == nil {
+		t.Fatalf("status = %q, delivered at %v; want delivered", delivery.Status, delivery.DeliveredAt)
+	}
+	if delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Errorf("attempts = %d, last error %q; want 3 attempts and no error", delivery.Attempts, delivery.LastError)
+	}
+}
+
+func TestFailsAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	worker := &WebhookWorker{Client: srv.Client(), MaxAttempts: 3, BaseDelay: time.Second}
+	hook := &models.Webhook{URL: srv.URL, Secret: "s3cret"}
+	delivery := testDelivery()
+
+	for i := 1; i <= 3; i++ {
+		code, err := worker.Send(hook, delivery)
+		delivery.StatusCode = code
+		worker.record(delivery, err)
+		want := "pending"
+		if i == 3 {
+			want = "failed"
+		}
+		if delivery.Status != want {
+			t.Fatalf("after attempt %d: status = %q, want %q", i, delivery.Status, want)
+		}
+	}
+	if delivery.DeliveredAt != nil {
+		t.Errorf("failed delivery has DeliveredAt set")
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	worker := &WebhookWorker{}
+	if got := worker.backoff(1); got != defaultWebhookBaseDelay {
+		t.Errorf("backoff(1) = %v, want %v", got, defaultWebhookBaseDelay)
+	}
+	if got := worker.backoff(20); got != maxWebhookDelay {
+		t.Errorf("backoff(20) = %v, want %v", got, maxWebhookDelay)
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	worker := &WebhookWorker{Client: NewWebhookClient(time.Second)}
+	if _, err := worker.Send(&models.Webhook{URL: srv.URL}, testDelivery()); err == nil {
+		t.Fatal("expected a delivery to a loopback address to fail")
+	}
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Error("the loopback server was reached")
+	}
+}
+
+func TestCheckWebhookHost(t *testing.T) {
+	blocked := []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://172.16.0.1/hook",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+	}
+	for _, raw := range blocked {
+		target, _ := url.Parse(raw)
+		if err := checkWebhookHost(target); err == nil {
+			t.Errorf("checkWebhookHost(%s) allowed a blocked address", raw)
+		}
+	}
+
+	target, _ := url.Parse("https://93.184.215.14/hook")
+	if err := checkWebhookHost(target); err != nil {
+		t.Errorf("checkWebhookHost(%s) = %v, want nil", target, err)
+	}
+}
@@ -1,11 +1,22 @@
 package utils
 import "golang.org/x/crypto/bcrypt"
 
+// PasswordCost is the bcrypt cost for new hashes. Each login pays for one
+// comparison at this cost, so it is kept where a check takes ~250ms.
+const PasswordCost = 12
+
 func HashPassword(password string) (string, error) {
-    bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+    bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
     return string(bytes), err
 }
 
+// NeedsRehash reports whether hash was made with a different cost and
+// should be replaced the next time the password is known.
+func NeedsRehash(hash string) bool {
+    cost, err := bcrypt.Cost([]byte(hash))
+    return err == nil && cost != PasswordCost
+}
+
 func CheckPassword(password, hash string) bool {
     err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
     return err == nil
@@ -8,29 +8,83 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userID string) (string, error) {
+// AccessTokenTTL is how long an access token is valid; clients renew it
+// with their refresh token.
+const AccessTokenTTL = 15 * time.Minute
+
+func jwtIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "splitwise"
+}
+
+func jwtAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "splitwise-api"
+}
+
+// GenerateJWT issues an access token for the user's session, signed with
+// the active key and tagged with its kid.
+func GenerateJWT(userID string, sessionID string) (string, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"sid":     sessionID,
+		"iss":     jwtIssuer(),
+		"aud":     jwtAudience(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	token := jwt.NewWithClaims(kr.active.Method, claims)
+	token.Header["kid"] = kr.active.ID
+	return token.SignedString(kr.active.Sign)
 }
 
-func ParseJWT(tokenStr string) (string, error) {
+// ParseJWT validates an access token and returns its user and session IDs.
+// The key is picked by kid, and the token must use that key's algorithm.
+func ParseJWT(tokenStr string) (string, string, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return "", "", err
+	}
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+		kid, _ := t.Header["kid"].(string)
+		key := kr.keys[kid]
+		if key == nil {
+			return nil, errors.New("unknown signing key")
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Verify, nil
+	},
+		jwt.WithValidMethods(kr.methods),
+		jwt.WithIssuer(jwtIssuer()),
+		jwt.WithAudience(jwtAudience()),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid token")
+		return "", "", errors.New("invalid token")
 	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return "", "", errors.New("invalid token claims")
 	}
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		return "", errors.New("invalid user_id in token")
+		return "", "", errors.New("invalid user_id in token")
+	}
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		return "", "", errors.New("invalid sid in token")
 	}
-	return userID, nil
+	return userID, sessionID, nil
 }
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one entry of the keyring. Public is set for asymmetric keys
+// and is what gets published in the JWKS.
+type signingKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Sign   interface{}
+	Verify interface{}
+	Public crypto.PublicKey
+}
+
+type keyring struct {
+	active  *signingKey
+	keys    map[string]*signingKey
+	methods []string
+}
+
+var (
+	ringOnce sync.Once
+	ring     *keyring
+	ringErr  error
+)
+
+// LoadKeys reads the signing keys from the environment. Tokens are signed
+// with JWT_ACTIVE_KID; every other key in JWT_KEYS still verifies tokens it
+// issued, so a key can be retired once its tokens have expired.
+//
+// JWT_KEYS is a comma-separated list of kid:alg:material entries. alg is
+// HS256, EdDSA or RS256; material is the secret for HS256 and a PKCS#8/PKCS#1
+// PEM private key (inline or a file path) otherwise. Without JWT_KEYS,
+// JWT_SECRET is used as a single HS256 key with kid "default".
+func LoadKeys() error {
+	ringOnce.Do(func() {
+		ring, ringErr = loadKeyring()
+	})
+	return ringErr
+}
+
+func loadKeyring() (*keyring, error) {
+	kr := &keyring{keys: map[string]*signingKey{}}
+
+	spec := os.Getenv("JWT_KEYS")
+	activeID := os.Getenv("JWT_ACTIVE_KID")
+	if spec == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_KEYS or JWT_SECRET must be set")
+		}
+		spec = "default:HS256:" + secret
+		activeID = "default"
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		key, err := parseKey(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		if kr.keys[key.ID] != nil {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			kr.methods = append(kr.methods, alg)
+		}
+	}
+
+	if activeID == "" && len(kr.keys) == 1 {
+		for id := range kr.keys {
+			activeID = id
+		}
+	}
+	kr.active = kr.keys[activeID]
+	if kr.active == nil {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID %q is not in JWT_KEYS", activeID)
+	}
+	return kr, nil
+}
+
+func parseKey(entry string) (*signingKey, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("jwt key %q must look like kid:alg:material", parts[0])
+	}
+	key := &signingKey{ID: parts[0]}
+
+	switch parts[1] {
+	case "HS256":
+		key.Method = jwt.SigningMethodHS256
+		key.Sign = []byte(parts[2])
+		key.Verify = key.Sign
+	case "EdDSA":
+		pem, err := readPEM(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		private, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", key.ID, err)
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.Sign = private
+		key.Public = private.(ed25519.PrivateKey).Public()
+		key.Verify = key.Public
+	case "RS256":
+		pem, err := readPEM(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", key.ID, err)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Sign = private
+		key.Public = &private.PublicKey
+		key.Verify = key.Public
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported algorithm %q", key.ID, parts[1])
+	}
+	return key, nil
+}
+
+// readPEM accepts either an inline PEM block, with newlines written as \n so
+// it fits in an environment variable, or a path to a PEM file.
+func readPEM(material string) ([]byte, error) {
+	if strings.HasPrefix(material, "-----BEGIN") {
+		return []byte(strings.ReplaceAll(material, `\n`, "\n")), nil
+	}
+	return os.ReadFile(material)
+}
+
+func currentKeyring() (*keyring, error) {
+	if err := LoadKeys(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// PublicJWKs returns the asymmetric keys of the keyring, so other services
+// can verify tokens without sharing a secret. HS256 keys are never listed.
+func PublicJWKs() ([]JWK, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	keys := []JWK{}
+	for _, key := range kr.keys {
+		switch public := key.Public.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP", Kid: key.ID, Alg: "EdDSA", Use: "sig", Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(public),
+			})
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA", Kid: key.ID, Alg: "RS256", Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	trustedProxiesOnce sync.Once
+	trustedProxies     []*net.IPNet
+	trustAnyProxy      bool
+)
+
+// loadTrustedProxies parses TRUSTED_PROXIES, a comma-separated list of
+// proxy IPs or CIDR ranges, or "*" for a single proxy at any address.
+func loadTrustedProxies() {
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			trustAnyProxy = true
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			trustedProxies = append(trustedProxies, network)
+		}
+	}
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the caller's address. X-Forwarded-For is ignored unless
+// TRUSTED_PROXIES is set, since any client can send it. It is read from the
+// right, as only the entries appended by our own proxies can be trusted and
+// clients can put anything before them. With TRUSTED_PROXIES=* a single
+// proxy is assumed, as on Render where its address isn't known, and the
+// right-most entry is the client; with a list, the header is only used when
+// the request came from one of those proxies, and the client is the
+// right-most entry that isn't one.
+func ClientIP(r *http.Request) string {
+	trustedProxiesOnce.Do(loadTrustedProxies)
+
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" {
+		return remote
+	}
+	hops := strings.Split(forwarded, ",")
+
+	if trustAnyProxy {
+		if hop := strings.TrimSpace(hops[len(hops)-1]); hop != "" {
+			return hop
+		}
+		return remote
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+	}
+	return strings.TrimSpace(hops[0])
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed
+// with secret. Receivers recompute it to check a delivery came from us and
+// use the timestamp to reject replays.
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
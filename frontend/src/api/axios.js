@@ -22,9 +22,22 @@ api.interceptors.response.use(
         }
         return response;
     },
-    (error) => {
+    async (error) => {
+        const original = error.config;
         if (error.response && error.response.status === 401) {
+            const refreshToken = localStorage.getItem('refresh_token');
+            if (refreshToken && original && !original._retry && !original.url.endsWith('/users/refresh')) {
+                original._retry = true;
+                try {
+                    const tokens = await refreshSession(refreshToken);
+                    original.headers.Authorization = `Bearer ${tokens.token}`;
+                    return api(original);
+                } catch (refreshErr) {
+                    // Fall through to signing out
+                }
+            }
             localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
             localStorage.removeItem('user');
             window.location.href = '/landing';
         }
@@ -32,4 +45,24 @@ api.interceptors.response.use(
     }
 );
 
+// Concurrent 401s share one refresh; refresh tokens are single-use, so a
+// second call with the same token would end the session.
+let refreshing = null;
+
+function refreshSession(refreshToken) {
+    if (!refreshing) {
+        refreshing = api
+            .post('/users/refresh', { refresh_token: refreshToken })
+            .then((response) => {
+                localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refresh_token', response.data.refresh_token);
+                return response.data;
+            })
+            .finally(() => {
+                refreshing = null;
+            });
+    }
+    return refreshing;
+}
+
 export default api;
@@ -4,6 +4,7 @@ import { CreditCard, LayoutDashboard, Users, LogOut, UserCheck, User, Menu, X }
 import { Button } from '../ui/Button';
 import { motion, AnimatePresence } from 'framer-motion';
 import { cn } from '../../utils/cn';
+import api from '../../api/axios';
 
 export const Navbar = () => {
     const location = useLocation();
@@ -20,8 +21,14 @@ export const Navbar = () => {
 
     if (!token) return null;
 
-    const handleLogout = () => {
+    const handleLogout = async () => {
+        try {
+            await api.post('/users/logout');
+        } catch (err) {
+            // The session is dropped locally either way
+        }
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         localStorage.removeItem('user');
         navigate('/landing');
     };
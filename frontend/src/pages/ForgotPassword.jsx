@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { Link, useNavigate } from 'react-router-dom';
+import { Link } from 'react-router-dom';
 import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
 import { Input } from '../components/ui/Input';
 import { Button } from '../components/ui/Button';
@@ -7,12 +7,10 @@ import { KeyRound, AlertCircle, ArrowLeft, CheckCircle2 } from 'lucide-react';
 import api from '../api/axios';
 
 const ForgotPassword = () => {
-    const navigate = useNavigate();
     const [email, setEmail] = useState('');
     const [error, setError] = useState('');
     const [loading, setLoading] = useState(false);
     const [success, setSuccess] = useState(false);
-    const [resetToken, setResetToken] = useState('');
 
     const handleSubmit = async (e) => {
         e.preventDefault();
@@ -20,11 +18,8 @@ const ForgotPassword = () => {
         setLoading(true);
 
         try {
-            const response = await api.post('/users/forgot-password', { email });
-            if (response.data && response.data.token) {
-                setResetToken(response.data.token);
-                setSuccess(true);
-            }
+            await api.post('/users/forgot-password', { email });
+            setSuccess(true);
         } catch (err) {
             setError(err.response?.data?.error || 'Failed to process request. Please try again.');
         } finally {
@@ -32,10 +27,6 @@ const ForgotPassword = () => {
         }
     };
 
-    const handleGoToReset = () => {
-        navigate('/reset-password', { state: { token: resetToken } });
-    };
-
     return (
         <div className="min-h-screen flex items-center justify-center p-4 selection:bg-emerald-100 selection:text-emerald-900 overflow-hidden relative">
             <Link to="/login" className="absolute top-6 left-6 flex items-center text-xs font-bold text-slate-400 hover:text-slate-700 transition-colors">
@@ -57,12 +48,12 @@ const ForgotPassword = () => {
                 <Card className="border-white/40 shadow-[0_8px_30px_rgb(0,0,0,0.08)] bg-white/70 backdrop-blur-xl">
                     <CardHeader className="text-center pb-2">
                         <CardTitle className="text-3xl font-extrabold tracking-tight">
-                            {success ? 'Token Generated' : 'Forgot Password'}
+                            {success ? 'Check Your Email' : 'Forgot Password'}
                         </CardTitle>
                         <p className="text-sm font-medium text-slate-500 mt-2">
                             {success
-                                ? 'Use the token below to reset your password'
-                                : 'Enter your email and we\'ll generate a reset token'}
+                                ? 'We\'ve sent you a link to reset your password'
+                                : 'Enter your email and we\'ll send you a reset link'}
                         </p>
                     </CardHeader>
                     <CardContent>
@@ -88,29 +79,15 @@ const ForgotPassword = () => {
                                 </div>
 
                                 <Button type="submit" className="w-full text-base py-5 mt-2" disabled={loading}>
-                                    {loading ? 'Sending...' : 'Get Reset Token'}
+                                    {loading ? 'Sending...' : 'Send Reset Link'}
                                 </Button>
                             </form>
                         ) : (
                             <div className="space-y-5 mt-4">
                                 <div className="p-3 bg-emerald-50 border border-emerald-200 text-emerald-700 rounded-lg text-sm flex items-start animate-in fade-in">
                                     <CheckCircle2 className="w-4 h-4 mr-2 mt-0.5 shrink-0" />
-                                    <span>Reset token generated successfully!</span>
-                                </div>
-
-                                <div className="space-y-2">
-                                    <label className="text-xs font-bold uppercase tracking-widest text-slate-500">Your Reset Token</label>
-                                    <div className="p-3 bg-slate-50 border border-slate-200 rounded-xl text-xs font-mono text-slate-700 break-all select-all">
-                                        {resetToken}
-                                    </div>
-                                    <p className="text-[11px] text-slate-400 font-medium">
-                                        Copy this token — you'll need it to reset your password.
-                                    </p>
+                                    <span>If an account exists for {email}, a password reset link is on its way. The link expires in 1 hour.</span>
                                 </div>
-
-                                <Button onClick={handleGoToReset} className="w-full text-base py-5">
-                                    Reset Password →
-                                </Button>
                             </div>
                         )}
 
@@ -53,6 +53,45 @@ const GroupDetail = () => {
         if (id) fetchData();
     }, [id]);
 
+    // Live updates: refetch whenever something changes in this group
+    // EventSource can't send headers, so each connection uses a single-use
+    // ticket; a dropped connection needs a fresh one rather than a retry.
+    useEffect(() => {
+        if (!id || !localStorage.getItem('token')) return;
+        let source;
+        let retry;
+        let closed = false;
+        const onEvent = (e) => {
+            const event = JSON.parse(e.data);
+            if (event.group_id === id) fetchData();
+        };
+        const types = [
+            'expense.created', 'expense.updated', 'expense.deleted',
+            'settlement.created', 'settlement.confirmed', 'settlement.rejected', 'settlement.deleted',
+            'group.member_added', 'group.member_removed', 'group.member_updated',
+        ];
+        const connect = async () => {
+            try {
+                const res = await api.post('/events/ticket');
+                if (closed) return;
+                source = new EventSource(`${api.defaults.baseURL}/events?ticket=${encodeURIComponent(res.data.ticket)}`);
+                types.forEach((type) => source.addEventListener(type, onEvent));
+                source.onerror = () => {
+                    source.close();
+                    if (!closed) retry = setTimeout(connect, 5000);
+                };
+            } catch (err) {
+                if (!closed) retry = setTimeout(connect, 5000);
+            }
+        };
+        connect();
+        return () => {
+            closed = true;
+            clearTimeout(retry);
+            if (source) source.close();
+        };
+    }, [id]);
+
     const fetchAllUsers = async () => {
         try {
             const res = await api.get('/users');
@@ -89,14 +128,21 @@ const GroupDetail = () => {
         return acc;
     }, {}), [allUsers]);
 
-    // Get member user objects from allUsers (since group.members = [string])
+    // Get member user objects from allUsers (group.members = [{user_id, role}])
     const memberObjects = useMemo(() => {
         if (!group?.members || allUsers.length === 0) return [];
         return group.members
-            .map(memberId => allUsers.find(u => u.id === memberId))
+            .map(member => {
+                const user = allUsers.find(u => u.id === member.user_id);
+                return user && { ...user, role: member.role };
+            })
             .filter(Boolean);
     }, [group, allUsers]);
 
+    // created_by stays the original creator; the member role says who runs the group now
+    const currentRole = group?.members?.find(m => m.user_id === currentUserId)?.role;
+    const canManageGroup = currentRole === 'owner' || currentRole === 'admin';
+
     const getUserName = (userId) => userMap[userId]?.name || userMap[userId]?.email || userId?.slice(0, 8) || 'Unknown';
 
     const handleAddExpense = async (e) => {
@@ -210,10 +256,10 @@ const GroupDetail = () => {
         }
     };
 
-    // group.members is [string] (array of user IDs), filter out already-members
+    // group.members is [{user_id, role}], filter out already-members
     const filteredUsers = useMemo(() => {
         if (!searchQuery) return [];
-        const memberIds = new Set(group?.members || []);
+        const memberIds = new Set((group?.members || []).map(m => m.user_id));
         return allUsers.filter(u =>
             !memberIds.has(u.id) &&
             (u.name?.toLowerCase().includes(searchQuery.toLowerCase()) ||
@@ -269,7 +315,7 @@ const GroupDetail = () => {
                                     ) : (
                                         <div className="flex items-center space-x-2">
                                             <h1 className="text-4xl font-black tracking-tight text-slate-800">{group.name}</h1>
-                                            {group.created_by === currentUserId && (
+                                            {canManageGroup && (
                                                 <button
                                                     onClick={() => { setRenameValue(group.name); setIsRenaming(true); }}
                                                     className="p-1.5 rounded-xl text-slate-300 hover:text-emerald-500 hover:bg-emerald-50 transition-all"
@@ -452,14 +498,14 @@ const GroupDetail = () => {
                                             <div>
                                                 <p className="text-sm font-extrabold text-slate-800">
                                                     {m.name}
-                                                    {m.id === group.created_by && (
-                                                        <span className="ml-2 text-[10px] font-black text-emerald-600 bg-emerald-50 px-2 py-0.5 rounded-full uppercase">Admin</span>
+                                                    {m.role && m.role !== 'member' && (
+                                                        <span className="ml-2 text-[10px] font-black text-emerald-600 bg-emerald-50 px-2 py-0.5 rounded-full uppercase">{m.role}</span>
                                                     )}
                                                 </p>
                                                 <p className="text-[10px] font-bold text-slate-400 uppercase tracking-widest">{m.email}</p>
                                             </div>
                                         </div>
-                                        {m.id !== group.created_by && (
+                                        {m.role !== 'owner' && (
                                             <button
                                                 onClick={() => handleRemoveMember(m.id)}
                                                 className="opacity-0 group-hover:opacity-100 p-2 text-rose-300 hover:text-rose-500 transition-all rounded-xl hover:bg-rose-50"
@@ -11,6 +11,8 @@ const Login = () => {
     const [formData, setFormData] = useState({ email: '', password: '' });
     const [error, setError] = useState('');
     const [loading, setLoading] = useState(false);
+    const [challengeToken, setChallengeToken] = useState('');
+    const [code, setCode] = useState('');
 
     React.useEffect(() => {
         if (localStorage.getItem('token')) {
@@ -24,9 +26,14 @@ const Login = () => {
         setLoading(true);
 
         try {
-            const response = await api.post('/users/login', formData);
-            if (response.data && response.data.token) {
+            const response = challengeToken
+                ? await api.post('/users/login/2fa', { challenge_token: challengeToken, code })
+                : await api.post('/users/login', formData);
+            if (response.data && response.data.two_factor_required) {
+                setChallengeToken(response.data.challenge_token);
+            } else if (response.data && response.data.token) {
                 localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refresh_token', response.data.refresh_token);
 
                 // Fetch user profile to get the name
                 try {
@@ -78,35 +85,53 @@ const Login = () => {
                                 </div>
                             )}
 
-                            <div className="space-y-2">
-                                <label className="text-sm font-medium text-slate-700" htmlFor="email">Email</label>
-                                <Input
-                                    id="email"
-                                    type="email"
-                                    placeholder="name@example.com"
-                                    required
-                                    value={formData.email}
-                                    onChange={(e) => setFormData({ ...formData, email: e.target.value })}
-                                />
-                            </div>
-                            <div className="space-y-2">
-                                <div className="flex items-center justify-between">
-                                    <label className="text-sm font-medium text-slate-700" htmlFor="password">Password</label>
-                                    <Link to="/forgot-password" className="text-xs font-semibold text-emerald-600 hover:text-emerald-500 hover:underline transition-all">
-                                        Forgot password?
-                                    </Link>
+                            {challengeToken ? (
+                                <div className="space-y-2">
+                                    <label className="text-sm font-medium text-slate-700" htmlFor="code">Authentication code</label>
+                                    <Input
+                                        id="code"
+                                        inputMode="numeric"
+                                        autoComplete="one-time-code"
+                                        placeholder="6-digit code or recovery code"
+                                        required
+                                        autoFocus
+                                        value={code}
+                                        onChange={(e) => setCode(e.target.value)}
+                                    />
                                 </div>
-                                <Input
-                                    id="password"
-                                    type="password"
-                                    required
-                                    value={formData.password}
-                                    onChange={(e) => setFormData({ ...formData, password: e.target.value })}
-                                />
-                            </div>
+                            ) : (
+                                <>
+                                    <div className="space-y-2">
+                                        <label className="text-sm font-medium text-slate-700" htmlFor="email">Email</label>
+                                        <Input
+                                            id="email"
+                                            type="email"
+                                            placeholder="name@example.com"
+                                            required
+                                            value={formData.email}
+                                            onChange={(e) => setFormData({ ...formData, email: e.target.value })}
+                                        />
+                                    </div>
+                                    <div className="space-y-2">
+                                        <div className="flex items-center justify-between">
+                                            <label className="text-sm font-medium text-slate-700" htmlFor="password">Password</label>
+                                            <Link to="/forgot-password" className="text-xs font-semibold text-emerald-600 hover:text-emerald-500 hover:underline transition-all">
+                                                Forgot password?
+                                            </Link>
+                                        </div>
+                                        <Input
+                                            id="password"
+                                            type="password"
+                                            required
+                                            value={formData.password}
+                                            onChange={(e) => setFormData({ ...formData, password: e.target.value })}
+                                        />
+                                    </div>
+                                </>
+                            )}
 
                             <Button type="submit" className="w-full text-base py-5 mt-2" disabled={loading}>
-                                {loading ? 'Signing in...' : 'Sign in'}
+                                {loading ? 'Signing in...' : challengeToken ? 'Verify' : 'Sign in'}
                             </Button>
                         </form>
 
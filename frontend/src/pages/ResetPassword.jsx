@@ -9,7 +9,7 @@ import api from '../api/axios';
 const ResetPassword = () => {
     const navigate = useNavigate();
     const location = useLocation();
-    const tokenFromState = location.state?.token || '';
+    const tokenFromState = location.state?.token || new URLSearchParams(location.search).get('token') || '';
 
     const [token, setToken] = useState(tokenFromState);
     const [newPassword, setNewPassword] = useState('');
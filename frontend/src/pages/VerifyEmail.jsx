@@ -0,0 +1,76 @@
+import React, { useEffect, useState } from 'react';
+import { Link, useLocation } from 'react-router-dom';
+import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
+import { MailCheck, AlertCircle, ArrowLeft, CheckCircle2 } from 'lucide-react';
+import api from '../api/axios';
+
+const VerifyEmail = () => {
+    const location = useLocation();
+    const token = new URLSearchParams(location.search).get('token') || '';
+
+    const [status, setStatus] = useState(token ? 'verifying' : 'error');
+    const [error, setError] = useState(token ? '' : 'This verification link is missing its token.');
+
+    useEffect(() => {
+        if (!token) return;
+        api.post('/users/verify-email', { token })
+            .then(() => setStatus('verified'))
+            .catch((err) => {
+                setError(err.response?.data?.error || 'Failed to verify your email. The link may have expired.');
+                setStatus('error');
+            });
+    }, [token]);
+
+    return (
+        <div className="min-h-screen flex items-center justify-center p-4 selection:bg-emerald-100 selection:text-emerald-900 overflow-hidden relative">
+            <Link to="/login" className="absolute top-6 left-6 flex items-center text-xs font-bold text-slate-400 hover:text-slate-700 transition-colors">
+                <ArrowLeft className="w-3.5 h-3.5 mr-1.5" />
+                Back to login
+            </Link>
+
+            <div className="absolute top-1/2 left-1/2 -translate-x-1/2 -translate-y-1/2 w-[600px] h-[600px] bg-emerald-300/20 rounded-full blur-[100px] pointer-events-none"></div>
+
+            <div className="w-full max-w-md animate-in fade-in zoom-in-95 duration-700 relative z-10">
+                <div className="flex justify-center mb-8 relative">
+                    <div className="absolute inset-0 bg-emerald-400/20 blur-xl rounded-full scale-150"></div>
+                    <div className="w-14 h-14 bg-gradient-to-br from-emerald-400 to-emerald-600 rounded-2xl flex items-center justify-center shadow-2xl relative z-10">
+                        <MailCheck className="w-7 h-7 text-white" />
+                    </div>
+                </div>
+
+                <Card className="border-white/40 shadow-[0_8px_30px_rgb(0,0,0,0.08)] bg-white/70 backdrop-blur-xl">
+                    <CardHeader className="text-center pb-2">
+                        <CardTitle className="text-3xl font-extrabold tracking-tight">
+                            {status === 'verified' ? 'Email Verified!' : 'Verify Email'}
+                        </CardTitle>
+                    </CardHeader>
+                    <CardContent>
+                        {status === 'verifying' && (
+                            <p className="text-sm text-center font-medium text-slate-500 mt-4">Verifying your email...</p>
+                        )}
+                        {status === 'verified' && (
+                            <div className="p-3 mt-4 bg-emerald-50 border border-emerald-200 text-emerald-700 rounded-lg text-sm flex items-start animate-in fade-in">
+                                <CheckCircle2 className="w-4 h-4 mr-2 mt-0.5 shrink-0" />
+                                <span>Thanks! Your email is verified and your account is fully active.</span>
+                            </div>
+                        )}
+                        {status === 'error' && (
+                            <div className="p-3 mt-4 bg-red-50 border border-red-200 text-red-600 rounded-lg text-sm flex items-start animate-in fade-in">
+                                <AlertCircle className="w-4 h-4 mr-2 mt-0.5 shrink-0" />
+                                <span>{error}</span>
+                            </div>
+                        )}
+
+                        <div className="mt-6 text-center text-sm text-slate-500">
+                            <Link to="/login" className="font-semibold text-emerald-600 hover:text-emerald-500 hover:underline transition-all">
+                                Continue to sign in
+                            </Link>
+                        </div>
+                    </CardContent>
+                </Card>
+            </div>
+        </div>
+    );
+};
+
+export default VerifyEmail;